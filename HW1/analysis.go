@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// dirtyTile is a tile that still has dirt on it together with what it costs to get there
+type dirtyTile struct {
+	tile
+	dirt      int
+	moves     int // shortest walk from the start
+	minEnergy int // -1 when the tile can't be reached from the start
+}
+
+// roomAnalysis holds everything we can say about a room before the cleaner moves
+type roomAnalysis struct {
+	components     [][]tile // groups of floor tiles connected to each other
+	startComponent int      // index into components, -1 if the cleaner starts inside a wall
+	minEnergy      [][]int  // energy needed to reach each tile from the start, -1 if unreachable
+	reachable      []dirtyTile
+	unreachable    []dirtyTile
+	reachableDirt  int
+	// collectibleBound is an upper bound on the dirt the cleaner can collect with its battery
+	collectibleBound int
+	collectibleTiles int
+}
+
+// analyzeRoom computes connected components, reachable dirt, minimum energy to every tile
+// and an upper bound on how much dirt the battery allows us to collect
+func analyzeRoom(c *Cleaner, room [][]string) roomAnalysis {
+	a := roomAnalysis{startComponent: -1}
+	start := tile{c.locationX, c.locationY}
+
+	// Label connected components with a flood fill
+	label := make([][]int, len(room))
+	for y := range room {
+		label[y] = make([]int, len(room[y]))
+		for x := range label[y] {
+			label[y][x] = -1
+		}
+	}
+	for y := range room {
+		for x := range room[y] {
+			if isBlocked(room, x, y) || label[y][x] != -1 {
+				continue
+			}
+			id := len(a.components)
+			component := []tile{{x, y}}
+			label[y][x] = id
+			for i := 0; i < len(component); i++ {
+				for _, n := range openNeighbors(room, component[i]) {
+					if label[n.y][n.x] == -1 {
+						label[n.y][n.x] = id
+						component = append(component, n)
+					}
+				}
+			}
+			a.components = append(a.components, component)
+		}
+	}
+	if inBounds(room, start.x, start.y) {
+		a.startComponent = label[start.y][start.x]
+	}
+
+	// Every move costs the same, so the cheapest route is the shortest one,
+	// the battery model says what that many moves drain from the current charge
+	moves := distanceMap(room, start)
+	a.minEnergy = make([][]int, len(moves))
+	for y := range moves {
+		a.minEnergy[y] = make([]int, len(moves[y]))
+		for x, d := range moves[y] {
+			a.minEnergy[y][x] = -1
+			if d >= 0 {
+				a.minEnergy[y][x] = c.predictedCost(d, 0)
+			}
+		}
+	}
+
+	for y := range room {
+		for x := range room[y] {
+			dirt := tileDirt(room, x, y)
+			if dirt <= 0 {
+				continue
+			}
+			dt := dirtyTile{tile: tile{x, y}, dirt: dirt, moves: moves[y][x], minEnergy: a.minEnergy[y][x]}
+			if dt.minEnergy < 0 {
+				a.unreachable = append(a.unreachable, dt)
+				continue
+			}
+			a.reachable = append(a.reachable, dt)
+			a.reachableDirt += dirt
+		}
+	}
+
+	a.collectibleBound, a.collectibleTiles = collectibleUpperBound(c, a.reachable)
+	return a
+}
+
+// collectibleUpperBound returns the most dirt we could possibly collect and from how many tiles.
+// Cleaning k tiles costs at least the cheapest trip to one of them, k vacuums and k-1 moves between
+// them, so we take the largest k the battery allows and assume we got the k dirtiest tiles.
+func collectibleUpperBound(c *Cleaner, reachable []dirtyTile) (int, int) {
+	var candidates []dirtyTile
+	cheapest := -1
+	for _, dt := range reachable {
+		if c.predictedCost(dt.moves, 1) > c.battery {
+			continue
+		}
+		candidates = append(candidates, dt)
+		if cheapest == -1 || dt.moves < cheapest {
+			cheapest = dt.moves
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].dirt > candidates[j].dirt
+	})
+
+	bound, count := 0, 0
+	for k := 1; k <= len(candidates); k++ {
+		if c.predictedCost(cheapest+k-1, k) > c.battery {
+			break
+		}
+		bound += candidates[k-1].dirt
+		count = k
+	}
+	return bound, count
+}
+
+func (a roomAnalysis) print() {
+	fmt.Println("Room analysis:")
+	fmt.Println("Connected components:", len(a.components))
+	for i, component := range a.components {
+		marker := ""
+		if i == a.startComponent {
+			marker = " (start)"
+		}
+		fmt.Printf("  component %d: %d tiles%s\n", i, len(component), marker)
+	}
+
+	fmt.Println("Reachable dirty tiles:", len(a.reachable), "with total dirt", a.reachableDirt)
+	for _, dt := range a.reachable {
+		fmt.Printf("  %v dirt %d, min energy %d\n", dt.tile, dt.dirt, dt.minEnergy)
+	}
+	fmt.Println("Unreachable dirty tiles (boxed in by walls or no-go zones):", len(a.unreachable))
+	for _, dt := range a.unreachable {
+		fmt.Printf("  %v dirt %d\n", dt.tile, dt.dirt)
+	}
+	fmt.Printf("Collectible dirt upper bound with current battery: %d (at most %d tiles)\n", a.collectibleBound, a.collectibleTiles)
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// batteryModel decides what an action really takes out of the battery and how charging behaves.
+// Energy values in room files are nominal, the model turns them into actual drain.
+type batteryModel interface {
+	// drain is what an action with the given nominal energy takes at the current charge
+	drain(charge, capacity, energy int) int
+	// chargeStep is how much one tick on a dock puts back
+	chargeStep(charge, capacity int) int
+	// fade is the capacity left after the given number of full charge cycles
+	fade(designCapacity int, cycles float64) int
+}
+
+// linearBattery is how the cleaner always worked, every action costs exactly its nominal energy
+type linearBattery struct{}
+
+func (linearBattery) drain(charge, capacity, energy int) int {
+	return energy
+}
+
+func (linearBattery) chargeStep(charge, capacity int) int {
+	return max(capacity/10, 1)
+}
+
+func (linearBattery) fade(designCapacity int, cycles float64) int {
+	return designCapacity
+}
+
+// curvedBattery behaves more like a real cell. Below lowCharge the voltage sags and actions cost up to
+// lowPenalty times more, charging is fast up to fastUntil and slow after, and every full cycle
+// takes fadePerCycle off the capacity.
+type curvedBattery struct {
+	lowCharge    float64 // state of charge where efficiency starts dropping
+	lowPenalty   float64 // extra cost factor at an empty battery
+	fastUntil    float64
+	fastRate     float64 // share of the capacity put back per tick while fast charging
+	slowRate     float64
+	fadePerCycle float64
+}
+
+func (b curvedBattery) drain(charge, capacity, energy int) int {
+	if capacity <= 0 {
+		return energy
+	}
+	soc := float64(charge) / float64(capacity)
+	if soc >= b.lowCharge {
+		return energy
+	}
+	factor := 1 + b.lowPenalty*(b.lowCharge-math.Max(soc, 0))/b.lowCharge
+	return int(math.Ceil(float64(energy) * factor))
+}
+
+func (b curvedBattery) chargeStep(charge, capacity int) int {
+	rate := b.slowRate
+	if float64(charge) < b.fastUntil*float64(capacity) {
+		rate = b.fastRate
+	}
+	return max(int(rate*float64(capacity)), 1)
+}
+
+func (b curvedBattery) fade(designCapacity int, cycles float64) int {
+	return int(math.Round(float64(designCapacity) * math.Pow(1-b.fadePerCycle, cycles)))
+}
+
+// batteryModels are the models the -battery flag can pick
+var batteryModels = map[string]batteryModel{
+	"linear": linearBattery{},
+	"nonlinear": curvedBattery{
+		lowCharge:    0.2,
+		lowPenalty:   1,
+		fastUntil:    0.8,
+		fastRate:     0.1,
+		slowRate:     0.02,
+		fadePerCycle: 0.02,
+	},
+}
+
+// activeBattery is the model every drain and prediction goes through
+var activeBattery batteryModel = linearBattery{}
+
+// socSample is the battery right after something changed it
+type socSample struct {
+	tick     int
+	charge   int
+	capacity int
+	charging bool
+}
+
+// initBattery treats whatever charge the room file gave as a full, new battery
+func (c *Cleaner) initBattery() {
+	c.capacity, c.designCapacity = c.battery, c.battery
+	c.socTimeline = []socSample{{c.ticks, c.battery, c.capacity, false}}
+}
+
+func (c *Cleaner) fullCharge() int {
+	return max(c.capacity, c.battery)
+}
+
+// cost is what an action with the given nominal energy would drain right now
+func (c *Cleaner) cost(energy int) int {
+	return activeBattery.drain(c.battery, c.fullCharge(), energy)
+}
+
+// spend drains the battery for an action, callers check cost first
+func (c *Cleaner) spend(energy int) {
+	c.battery -= c.cost(energy)
+	c.socTimeline = append(c.socTimeline, socSample{c.ticks, c.battery, c.fullCharge(), false})
+}
+
+// predictedCost is the drain of making the moves and then vacuuming, starting from the current charge.
+// Planners use it instead of multiplying the nominal energy since the drain grows as the charge drops.
+func (c *Cleaner) predictedCost(moves, vacuums int) int {
+	charge, capacity := c.battery, c.fullCharge()
+	for i := 0; i < moves+vacuums; i++ {
+		energy := c.movementEnergy
+		if i >= moves {
+			energy = c.vacuumEnergy
+		}
+		charge -= activeBattery.drain(charge, capacity, energy)
+	}
+	return c.battery - charge
+}
+
+// chargeAtDock fills the battery one tick at a time. Every capacity's worth of charge counts as
+// a cycle and the model may shrink the capacity as the cycles add up.
+func (c *Cleaner) chargeAtDock() {
+	c.charges++
+	for c.battery < c.capacity {
+		step := min(activeBattery.chargeStep(c.battery, c.capacity), c.capacity-c.battery)
+		c.battery += step
+		c.charged += step
+		c.ticks++
+		c.capacity = activeBattery.fade(c.designCapacity, float64(c.charged)/float64(max(c.designCapacity, 1)))
+		c.battery = min(c.battery, c.capacity)
+		c.socTimeline = append(c.socTimeline, socSample{c.ticks, c.battery, c.capacity, true})
+	}
+	fmt.Printf("Charged to %d of %d at the dock\n", c.battery, c.capacity)
+}
+
+// nearestDock returns the dock with the shortest walk from the tile and the number of moves, -1 if none is reachable
+func nearestDock(room [][]string, from tile) (tile, int) {
+	dist := distanceMap(room, from)
+	best, moves := tile{}, -1
+	for _, dock := range currentRoom.docks {
+		if !inBounds(room, dock.x, dock.y) {
+			continue
+		}
+		if d := dist[dock.y][dock.x]; d >= 0 && (moves == -1 || d < moves) {
+			best, moves = dock, d
+		}
+	}
+	return best, moves
+}
+
+// rechargeIfNeeded sends the cleaner to the nearest dock when the path it is about to take would not
+// leave enough charge to get back. It returns the tiles walked to the dock and whether the cleaner charged.
+func (c *Cleaner) rechargeIfNeeded(room [][]string, path []string) ([]string, bool) {
+	if len(currentRoom.docks) == 0 || len(path) == 0 {
+		return nil, false
+	}
+	target, err := parseTile(path[len(path)-1])
+	if err != nil {
+		return nil, false
+	}
+	moves, vacuums := 0, 0
+	for _, node := range path {
+		t, err := parseTile(node)
+		if err != nil || (t.x == c.locationX && t.y == c.locationY) {
+			continue
+		}
+		moves++
+		if tileDirt(room, t.x, t.y) > 0 {
+			vacuums++
+		}
+	}
+	_, back := nearestDock(room, target)
+	if back < 0 || c.predictedCost(moves+back, vacuums) <= c.battery {
+		return nil, false
+	}
+
+	dock, _ := nearestDock(room, tile{c.locationX, c.locationY})
+	if dock.x == c.locationX && dock.y == c.locationY && c.battery == c.capacity {
+		return nil, false
+	}
+	fmt.Println("Going back to the dock at", dock, "to charge")
+	var walked []string
+	for _, next := range shortestPath(room, tile{c.locationX, c.locationY}, dock) {
+		c.moveSomewhere(next.String(), room)
+		if c.locationX != next.x || c.locationY != next.y {
+			fmt.Println("Not enough battery to reach the dock.")
+			return walked, false
+		}
+		walked = append(walked, next.String())
+	}
+	c.chargeAtDock()
+	return walked, true
+}
+
+// printCharge shows the state of charge over the run, a row per sample but never more than
+// about twenty rows so long runs stay readable
+func (c *Cleaner) printCharge() {
+	fmt.Println("State of charge timeline:")
+	step := max(len(c.socTimeline)/20, 1)
+	for i, s := range c.socTimeline {
+		if i%step != 0 && i != len(c.socTimeline)-1 && !(s.charging && (i == 0 || !c.socTimeline[i-1].charging)) {
+			continue
+		}
+		percent := 0
+		if s.capacity > 0 {
+			percent = 100 * s.charge / s.capacity
+		}
+		note := ""
+		if s.charging {
+			note = " charging"
+		}
+		fmt.Printf("  tick %4d %5d/%-5d %3d%% %-20s%s\n", s.tick, s.charge, s.capacity, percent, strings.Repeat("#", percent/5), note)
+	}
+	fmt.Printf("Charges: %d, charge cycles: %.2f, capacity %d of %d\n", c.charges, float64(c.charged)/float64(max(c.designCapacity, 1)), c.capacity, c.designCapacity)
+}
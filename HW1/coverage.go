@@ -0,0 +1,266 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// segment is a vertical run of free tiles in one column, from top to bottom inclusive
+type segment struct {
+	x, top, bottom int
+}
+
+func (s segment) overlaps(other segment) bool {
+	return s.top <= other.bottom && other.top <= s.bottom
+}
+
+// coverageCell is one cell of the boustrophedon decomposition, a run of column segments
+// that can be swept back and forth without hitting a wall
+type coverageCell struct {
+	segments  []segment
+	neighbors []int
+}
+
+// decomposeRoom splits the free space into boustrophedon cells by sweeping a line from left to right.
+// A cell ends whenever the connectivity of the sweep line changes, meaning a segment splits or merges.
+func decomposeRoom(room [][]string) []coverageCell {
+	width := 0
+	for _, row := range room {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+
+	var cells []coverageCell
+	var previous []segment
+	var previousCells []int
+	for x := 0; x < width; x++ {
+		// Collect the free segments of this column
+		var current []segment
+		for y := 0; y < len(room); y++ {
+			if isBlocked(room, x, y) {
+				continue
+			}
+			if len(current) > 0 && current[len(current)-1].bottom == y-1 {
+				current[len(current)-1].bottom = y
+			} else {
+				current = append(current, segment{x, y, y})
+			}
+		}
+
+		currentCells := make([]int, len(current))
+		for i, seg := range current {
+			var touching []int
+			for j, prev := range previous {
+				if seg.overlaps(prev) {
+					touching = append(touching, j)
+				}
+			}
+
+			// Continue the cell only if both sides have exactly one partner
+			if len(touching) == 1 {
+				partners := 0
+				for _, other := range current {
+					if other.overlaps(previous[touching[0]]) {
+						partners++
+					}
+				}
+				if partners == 1 {
+					cell := previousCells[touching[0]]
+					cells[cell].segments = append(cells[cell].segments, seg)
+					currentCells[i] = cell
+					continue
+				}
+			}
+
+			cell := len(cells)
+			cells = append(cells, coverageCell{segments: []segment{seg}})
+			currentCells[i] = cell
+			for _, j := range touching {
+				other := previousCells[j]
+				cells[cell].neighbors = append(cells[cell].neighbors, other)
+				cells[other].neighbors = append(cells[other].neighbors, cell)
+			}
+		}
+		previous = current
+		previousCells = currentCells
+	}
+	return cells
+}
+
+// coverageOrder visits the cells depth first starting from the one containing the start tile
+func coverageOrder(cells []coverageCell, start tile) []int {
+	first := -1
+	for i, cell := range cells {
+		for _, seg := range cell.segments {
+			if seg.x == start.x && seg.top <= start.y && start.y <= seg.bottom {
+				first = i
+			}
+		}
+	}
+	if first == -1 {
+		return nil
+	}
+
+	var order []int
+	visited := make([]bool, len(cells))
+	var visit func(cell int)
+	visit = func(cell int) {
+		visited[cell] = true
+		order = append(order, cell)
+		neighbors := append([]int(nil), cells[cell].neighbors...)
+		sort.Ints(neighbors)
+		for _, n := range neighbors {
+			if !visited[n] {
+				visit(n)
+			}
+		}
+	}
+	visit(first)
+	return order
+}
+
+// sweepCell returns the lawnmower pattern for a cell, going down one column and up the next.
+// The first column starts from whichever end is closer to where the cleaner comes from.
+func sweepCell(cell coverageCell, from tile) []tile {
+	first := cell.segments[0]
+	down := abs(from.y-first.top) <= abs(from.y-first.bottom)
+
+	var result []tile
+	for _, seg := range cell.segments {
+		if down {
+			for y := seg.top; y <= seg.bottom; y++ {
+				result = append(result, tile{seg.x, y})
+			}
+		} else {
+			for y := seg.bottom; y >= seg.top; y-- {
+				result = append(result, tile{seg.x, y})
+			}
+		}
+		down = !down
+	}
+	return result
+}
+
+// shortestPath returns the tiles after from leading to to, or nil if to can't be reached
+func shortestPath(room [][]string, from, to tile) []tile {
+	if from == to {
+		return nil
+	}
+	dist := distanceMap(room, to)
+	if dist[from.y][from.x] == -1 {
+		return nil
+	}
+
+	// Walk downhill on the distance map from the start towards the goal
+	var path []tile
+	current := from
+	for current != to {
+		for _, n := range openNeighbors(room, current) {
+			if dist[n.y][n.x] == dist[current.y][current.x]-1 {
+				current = n
+				break
+			}
+		}
+		path = append(path, current)
+	}
+	return path
+}
+
+// coveragePath plans the full boustrophedon route over every tile reachable from start
+func coveragePath(room [][]string, start tile) []tile {
+	cells := decomposeRoom(room)
+	current := start
+	var route []tile
+	for _, cell := range coverageOrder(cells, start) {
+		for _, next := range sweepCell(cells[cell], current) {
+			if next == current {
+				continue
+			}
+			// Connect to the next sweep tile, going around walls when the columns don't line up
+			if abs(next.x-current.x)+abs(next.y-current.y) == 1 {
+				route = append(route, next)
+			} else {
+				route = append(route, shortestPath(room, current, next)...)
+			}
+			current = next
+		}
+	}
+	return route
+}
+
+// cleanWithCoverage sweeps the whole reachable floor, vacuuming every dirty tile it passes
+func (c *Cleaner) cleanWithCoverage(roomData [][]string) []string {
+	c.recordTrail()
+	c.decideToClean(roomData)
+
+	totalPath := []string{tile{c.locationX, c.locationY}.String()}
+	for _, next := range coveragePath(roomData, tile{c.locationX, c.locationY}) {
+		c.moveSomewhere(next.String(), roomData)
+		if c.locationX != next.x || c.locationY != next.y {
+			fmt.Println("Coverage stopped, not enough battery to continue the sweep.")
+			break
+		}
+		c.decideToClean(roomData)
+		totalPath = append(totalPath, next.String())
+	}
+	return totalPath
+}
+
+// cleaningStats summarises one run of a cleaning strategy
+type cleaningStats struct {
+	name         string
+	freeTiles    int // tiles reachable from the start
+	visitedTiles int // distinct tiles the cleaner stood on
+	revisits     int // moves onto a tile that was already visited
+	energy       int
+	dirt         int
+	tilesCleaned int
+}
+
+func (s cleaningStats) coverage() float64 {
+	if s.freeTiles == 0 {
+		return 0
+	}
+	return 100 * float64(s.visitedTiles) / float64(s.freeTiles)
+}
+
+// measureRun runs a cleaning strategy and records how well it covered the room
+func (c *Cleaner) measureRun(name string, room [][]string, strategy func(*Cleaner, [][]string) []string) (cleaningStats, []string) {
+	stats := cleaningStats{name: name}
+	for _, row := range distanceMap(room, tile{c.locationX, c.locationY}) {
+		for _, d := range row {
+			if d >= 0 {
+				stats.freeTiles++
+			}
+		}
+	}
+
+	batteryBefore, dirtBefore, cleanedBefore, trailBefore := c.battery, c.dirtVolume, c.tilesCleaned, len(c.trail)
+	path := strategy(c, room)
+
+	trail := c.trail
+	if trailBefore > 0 {
+		trail = c.trail[trailBefore-1:]
+	}
+	seen := make(map[tile]bool)
+	for _, t := range trail {
+		if seen[t] {
+			stats.revisits++
+		}
+		seen[t] = true
+	}
+	stats.visitedTiles = len(seen)
+	stats.energy = batteryBefore - c.battery
+	stats.dirt = c.dirtVolume - dirtBefore
+	stats.tilesCleaned = c.tilesCleaned - cleanedBefore
+	return stats, path
+}
+
+func printComparison(runs []cleaningStats) {
+	fmt.Println("Strategy comparison:")
+	fmt.Printf("%-10s %9s %8s %8s %8s %8s\n", "strategy", "coverage", "revisits", "energy", "dirt", "cleaned")
+	for _, s := range runs {
+		fmt.Printf("%-10s %8.1f%% %8d %8d %8d %8d\n", s.name, s.coverage(), s.revisits, s.energy, s.dirt, s.tilesCleaned)
+	}
+}
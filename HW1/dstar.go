@@ -0,0 +1,304 @@
+package main
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+)
+
+// dstarInf stands for an unreachable distance, small enough that adding a step can't overflow
+const dstarInf = math.MaxInt32 / 2
+
+type dstarKey [2]int
+
+func (k dstarKey) less(other dstarKey) bool {
+	return k[0] < other[0] || (k[0] == other[0] && k[1] < other[1])
+}
+
+type dstarEntry struct {
+	at  tile
+	key dstarKey
+}
+
+// dstarQueue is a min heap of entries, stale entries are skipped when popped
+type dstarQueue []dstarEntry
+
+func (q dstarQueue) Len() int            { return len(q) }
+func (q dstarQueue) Less(i, j int) bool  { return q[i].key.less(q[j].key) }
+func (q dstarQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *dstarQueue) Push(x interface{}) { *q = append(*q, x.(dstarEntry)) }
+func (q *dstarQueue) Pop() interface{} {
+	old := *q
+	entry := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return entry
+}
+
+// dstarLite is an incremental planner that searches backwards from the goals, so when walls
+// change it only repairs the part of the search that the change affected.
+// It follows the basic version from Koenig and Likhachev, "D* Lite" (AAAI 2002).
+type dstarLite struct {
+	room     [][]string
+	goals    map[tile]bool
+	start    tile
+	last     tile
+	km       int
+	g, rhs   [][]int
+	queue    dstarQueue
+	queued   map[tile]dstarKey // current key of every tile in the queue
+	expanded int               // tiles taken off the queue and expanded, the work done so far
+}
+
+func newDStarLite(room [][]string, start tile, goals []tile) *dstarLite {
+	d := &dstarLite{
+		room:   room,
+		goals:  make(map[tile]bool),
+		start:  start,
+		last:   start,
+		queued: make(map[tile]dstarKey),
+	}
+	d.g = make([][]int, len(room))
+	d.rhs = make([][]int, len(room))
+	for y := range room {
+		d.g[y] = make([]int, len(room[y]))
+		d.rhs[y] = make([]int, len(room[y]))
+		for x := range room[y] {
+			d.g[y][x] = dstarInf
+			d.rhs[y][x] = dstarInf
+		}
+	}
+	for _, goal := range goals {
+		d.goals[goal] = true
+		d.rhs[goal.y][goal.x] = 0
+		d.push(goal)
+	}
+	return d
+}
+
+// heuristic is the Manhattan distance from the cleaner, the search runs towards it
+func (d *dstarLite) heuristic(t tile) int {
+	return abs(t.x-d.start.x) + abs(t.y-d.start.y)
+}
+
+func (d *dstarLite) calculateKey(t tile) dstarKey {
+	best := min(d.g[t.y][t.x], d.rhs[t.y][t.x])
+	return dstarKey{best + d.heuristic(t) + d.km, best}
+}
+
+func (d *dstarLite) push(t tile) {
+	key := d.calculateKey(t)
+	d.queued[t] = key
+	heap.Push(&d.queue, dstarEntry{t, key})
+}
+
+// top drops stale entries and returns the smallest live one
+func (d *dstarLite) top() (dstarEntry, bool) {
+	for d.queue.Len() > 0 {
+		entry := d.queue[0]
+		if key, ok := d.queued[entry.at]; ok && key == entry.key {
+			return entry, true
+		}
+		heap.Pop(&d.queue)
+	}
+	return dstarEntry{}, false
+}
+
+// cost of stepping between two neighbouring tiles
+func (d *dstarLite) cost(a, b tile) int {
+	if isBlocked(d.room, a.x, a.y) || isBlocked(d.room, b.x, b.y) {
+		return dstarInf
+	}
+	return stepCost(b.x, b.y)
+}
+
+func (d *dstarLite) neighbors(t tile) []tile {
+	var result []tile
+	for _, dir := range directions4 {
+		n := tile{t.x + dir.x, t.y + dir.y}
+		if inBounds(d.room, n.x, n.y) {
+			result = append(result, n)
+		}
+	}
+	return result
+}
+
+func (d *dstarLite) updateVertex(t tile) {
+	if !d.goals[t] {
+		best := dstarInf
+		for _, n := range d.neighbors(t) {
+			best = min(best, min(dstarInf, d.cost(t, n)+d.g[n.y][n.x]))
+		}
+		d.rhs[t.y][t.x] = best
+	}
+	delete(d.queued, t)
+	if d.g[t.y][t.x] != d.rhs[t.y][t.x] {
+		d.push(t)
+	}
+}
+
+func (d *dstarLite) computeShortestPath() {
+	for {
+		entry, ok := d.top()
+		startKey := d.calculateKey(d.start)
+		if !ok || (!entry.key.less(startKey) && d.rhs[d.start.y][d.start.x] == d.g[d.start.y][d.start.x]) {
+			return
+		}
+		u := entry.at
+		heap.Pop(&d.queue)
+		delete(d.queued, u)
+
+		if newKey := d.calculateKey(u); entry.key.less(newKey) {
+			d.push(u)
+			continue
+		}
+		d.expanded++
+		if d.g[u.y][u.x] > d.rhs[u.y][u.x] {
+			d.g[u.y][u.x] = d.rhs[u.y][u.x]
+			for _, n := range d.neighbors(u) {
+				d.updateVertex(n)
+			}
+		} else {
+			d.g[u.y][u.x] = dstarInf
+			d.updateVertex(u)
+			for _, n := range d.neighbors(u) {
+				d.updateVertex(n)
+			}
+		}
+	}
+}
+
+// next returns the neighbour to step onto, false when no goal can be reached
+func (d *dstarLite) next() (tile, bool) {
+	if d.g[d.start.y][d.start.x] >= dstarInf {
+		return tile{}, false
+	}
+	best, bestCost := tile{}, dstarInf
+	for _, n := range d.neighbors(d.start) {
+		if c := d.cost(d.start, n) + d.g[n.y][n.x]; c < bestCost {
+			best, bestCost = n, c
+		}
+	}
+	return best, bestCost < dstarInf
+}
+
+// moveTo tells the planner the cleaner moved, the heuristic shift is stored in km
+func (d *dstarLite) moveTo(t tile) {
+	d.start = t
+}
+
+// wallsChanged repairs the search after the given tiles turned into walls or floor
+func (d *dstarLite) wallsChanged(changed []tile) {
+	d.km += abs(d.last.x-d.start.x) + abs(d.last.y-d.start.y)
+	d.last = d.start
+	for _, t := range changed {
+		d.updateVertex(t)
+		for _, n := range d.neighbors(t) {
+			d.updateVertex(n)
+		}
+	}
+	d.computeShortestPath()
+}
+
+// replanStats compares the incremental repairs with searching again from scratch
+type replanStats struct {
+	replans         int
+	incremental     int // tiles expanded by D* Lite repairing its search
+	fromScratch     int // tiles a fresh search would have expanded for the same changes
+	initialSearches int
+}
+
+func (s replanStats) print() {
+	fmt.Println("Replanning stats:")
+	fmt.Println("Initial searches:", s.initialSearches)
+	fmt.Println("Replans after wall changes:", s.replans)
+	fmt.Println("Tiles expanded by incremental replanning:", s.incremental)
+	fmt.Println("Tiles expanded by full replanning:", s.fromScratch)
+	if s.fromScratch > 0 {
+		fmt.Printf("Incremental replanning saved %.1f%% of the work\n", 100*(1-float64(s.incremental)/float64(s.fromScratch)))
+	}
+}
+
+// dirtiestTiles returns every tile holding the most dirt, the same targets AStar picks
+func dirtiestTiles(room [][]string) []tile {
+	maxDirt := 0
+	var result []tile
+	for y := range room {
+		for x := range room[y] {
+			dirt := tileDirt(room, x, y)
+			if dirt <= 0 || isBlocked(room, x, y) {
+				continue
+			}
+			if dirt > maxDirt {
+				maxDirt = dirt
+				result = []tile{{x, y}}
+			} else if dirt == maxDirt {
+				result = append(result, tile{x, y})
+			}
+		}
+	}
+	return result
+}
+
+// cleanWithDStar chases the dirtiest tiles like the AStar loop, but keeps one D* Lite search per target
+// and repairs it when scripted events move walls instead of searching again
+func (c *Cleaner) cleanWithDStar(roomData [][]string) ([]string, replanStats) {
+	var stats replanStats
+	c.recordTrail()
+	totalPath := []string{tile{c.locationX, c.locationY}.String()}
+	for c.battery > 0 {
+		c.applyDueEvents(roomData)
+		goals := dirtiestTiles(roomData)
+		if len(goals) == 0 {
+			fmt.Println("No more paths to dirtiest tiles.")
+			break
+		}
+		planner := newDStarLite(roomData, tile{c.locationX, c.locationY}, goals)
+		planner.computeShortestPath()
+		stats.initialSearches++
+
+		for !planner.goals[tile{c.locationX, c.locationY}] {
+			if changed := c.applyDueEvents(roomData); len(changed) > 0 {
+				before := planner.expanded
+				planner.wallsChanged(changed)
+				stats.replans++
+				stats.incremental += planner.expanded - before
+
+				fresh := newDStarLite(roomData, tile{c.locationX, c.locationY}, goals)
+				fresh.computeShortestPath()
+				stats.fromScratch += fresh.expanded
+			}
+
+			next, ok := planner.next()
+			if !ok {
+				break
+			}
+			c.moveSomewhere(next.String(), roomData)
+			if c.locationX != next.x || c.locationY != next.y {
+				break
+			}
+			planner.moveTo(next)
+			totalPath = append(totalPath, next.String())
+			if !planner.goals[next] {
+				c.decideToClean(roomData)
+			}
+		}
+
+		if !planner.goals[tile{c.locationX, c.locationY}] {
+			if c.battery >= c.cost(c.movementEnergy) && c.waitForNextEvent(roomData) {
+				continue
+			}
+			if c.battery < c.cost(c.movementEnergy) {
+				fmt.Println("Not enough battery to reach the dirtiest tiles.")
+			} else {
+				fmt.Println("No more paths to dirtiest tiles.")
+			}
+			break
+		}
+		c.decideToClean(roomData)
+		if tileDirt(roomData, c.locationX, c.locationY) > 0 {
+			fmt.Println("Not enough battery to clean the dirtiest tile.")
+			break
+		}
+	}
+	return totalPath, stats
+}
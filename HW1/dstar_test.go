@@ -0,0 +1,56 @@
+package main
+
+import "testing"
+
+// TestDStarLiteMatchesAStar changes walls under a D* Lite search, moving the cleaner in between, and
+// checks the repaired cost from the cleaner to the dirt is what a fresh A* search finds
+func TestDStarLiteMatchesAStar(t *testing.T) {
+	activeOverlay = nil
+	room := make([][]string, 5)
+	for y := range room {
+		room[y] = []string{"0", "0", "0", "0", "0", "0", "0"}
+	}
+	room[2][6] = "50"
+
+	d := newDStarLite(room, tile{0, 2}, dirtiestTiles(room))
+	d.computeShortestPath()
+
+	steps := []struct {
+		name  string
+		moves int
+		walls []tile
+		add   bool
+	}{
+		{name: "no change"},
+		{name: "wall across the middle", walls: []tile{{3, 1}, {3, 2}, {3, 3}}, add: true},
+		{name: "top row closed after a step", moves: 1, walls: []tile{{3, 0}}, add: true},
+		{name: "gap opened again after two steps", moves: 2, walls: []tile{{3, 2}}},
+	}
+	for _, step := range steps {
+		for i := 0; i < step.moves; i++ {
+			next, ok := d.next()
+			if !ok {
+				t.Fatalf("%s: D* Lite has no step from %v", step.name, d.start)
+			}
+			d.moveTo(next)
+		}
+		for _, w := range step.walls {
+			if step.add {
+				room[w.y][w.x] = wallValue
+			} else {
+				room[w.y][w.x] = "0"
+			}
+		}
+		if len(step.walls) > 0 {
+			d.wallsChanged(step.walls)
+		}
+
+		path := AStar(d.start.x, d.start.y, room)
+		if path == nil {
+			t.Fatalf("%s: A* finds no path from %v", step.name, d.start)
+		}
+		if got, want := d.g[d.start.y][d.start.x], len(path)-1; got != want {
+			t.Errorf("%s: D* Lite costs %d from %v, A* %d", step.name, got, d.start, want)
+		}
+	}
+}
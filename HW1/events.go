@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// obstacleEvent adds or removes a wall once the simulation clock reaches tick
+type obstacleEvent struct {
+	tick int
+	add  bool
+	at   tile
+}
+
+// obstacleEvents are the scripted changes for the current run, sorted by tick
+var obstacleEvents []obstacleEvent
+
+// readEventsFile loads events written one per line as tick,add|remove,x,y
+func readEventsFile(filePath string) []obstacleEvent {
+	f, err := os.Open(filePath)
+	if err != nil {
+		log.Fatal("Unable to read events file "+filePath, err)
+	}
+	defer func(f *os.File) {
+		err := f.Close()
+		if err != nil {
+			fmt.Println("Error closing file:", err)
+		}
+	}(f)
+
+	csvReader := csv.NewReader(f)
+	csvReader.FieldsPerRecord = -1
+	csvReader.Comment = '#'
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		log.Fatal("Error reading events data", err)
+	}
+
+	var events []obstacleEvent
+	for _, record := range records {
+		if len(record) != 4 {
+			fmt.Println("Skipping event, expected tick,action,x,y:", record)
+			continue
+		}
+		values := make([]int, 0, 3)
+		for _, field := range []string{record[0], record[2], record[3]} {
+			value, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil {
+				fmt.Println("Error converting event value to int:", err)
+				break
+			}
+			values = append(values, value)
+		}
+		if len(values) != 3 {
+			continue
+		}
+		action := strings.TrimSpace(record[1])
+		if action != "add" && action != "remove" {
+			fmt.Println("Skipping event with unknown action:", action)
+			continue
+		}
+		events = append(events, obstacleEvent{tick: values[0], add: action == "add", at: tile{values[1], values[2]}})
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].tick < events[j].tick
+	})
+	return events
+}
+
+// applyDueEvents changes the room for every event whose tick has come and returns the tiles that changed
+func (c *Cleaner) applyDueEvents(room [][]string) []tile {
+	var changed []tile
+	for len(obstacleEvents) > 0 && obstacleEvents[0].tick <= c.ticks {
+		event := obstacleEvents[0]
+		obstacleEvents = obstacleEvents[1:]
+		if !inBounds(room, event.at.x, event.at.y) {
+			fmt.Println("Skipping event outside the room at", event.at)
+			continue
+		}
+		if event.add {
+			if event.at == (tile{c.locationX, c.locationY}) {
+				fmt.Println("Skipping wall at", event.at, "the cleaner is standing there")
+				continue
+			}
+			if isWall(room, event.at.x, event.at.y) {
+				continue
+			}
+			room[event.at.y][event.at.x] = wallValue
+			fmt.Println("Tick", c.ticks, "wall added at", event.at)
+		} else {
+			if !isWall(room, event.at.x, event.at.y) {
+				continue
+			}
+			room[event.at.y][event.at.x] = "0"
+			fmt.Println("Tick", c.ticks, "wall removed at", event.at)
+		}
+		changed = append(changed, event.at)
+		roomEdits++
+	}
+	return changed
+}
+
+// waitForNextEvent lets the cleaner sit still until the next scripted event and applies it,
+// false means there is nothing left to wait for
+func (c *Cleaner) waitForNextEvent(room [][]string) bool {
+	for len(obstacleEvents) > 0 {
+		if c.ticks < obstacleEvents[0].tick {
+			fmt.Println("Waiting until tick", obstacleEvents[0].tick, "for the room to change")
+			c.ticks = obstacleEvents[0].tick
+		}
+		if len(c.applyDueEvents(room)) > 0 {
+			return true
+		}
+	}
+	return false
+}
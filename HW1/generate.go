@@ -0,0 +1,61 @@
+package main
+
+import (
+	"math/rand"
+	"strconv"
+)
+
+// generateRoom builds a large floor plan for benchmarks: a grid of rooms separated by walls
+// with a doorway in every wall, some furniture dropped inside and dirt scattered around.
+// The same seed always gives the same room.
+func generateRoom(width, height int, seed int64) [][]string {
+	rng := rand.New(rand.NewSource(seed))
+	const roomSize = 12
+
+	room := make([][]string, height)
+	for y := range room {
+		room[y] = make([]string, width)
+		for x := range room[y] {
+			room[y][x] = "0"
+			if x%roomSize == roomSize-1 || y%roomSize == roomSize-1 {
+				room[y][x] = wallValue
+			}
+		}
+	}
+
+	// Cut a doorway into every wall segment between two rooms
+	for top := 0; top < height; top += roomSize {
+		for left := 0; left < width; left += roomSize {
+			wallX, wallY := left+roomSize-1, top+roomSize-1
+			if wallX < width {
+				y := top + rng.Intn(roomSize-1)
+				if y < height {
+					room[y][wallX] = "0"
+				}
+			}
+			if wallY < height {
+				x := left + rng.Intn(roomSize-1)
+				if x < width {
+					room[wallY][x] = "0"
+				}
+			}
+		}
+	}
+
+	// Furniture and dirt, kept off the tiles next to doorways so rooms stay connected
+	for y := 1; y < height-1; y++ {
+		for x := 1; x < width-1; x++ {
+			if room[y][x] != "0" || x%roomSize == 0 || y%roomSize == 0 ||
+				x%roomSize == roomSize-2 || y%roomSize == roomSize-2 {
+				continue
+			}
+			switch r := rng.Float64(); {
+			case r < 0.08:
+				room[y][x] = wallValue
+			case r < 0.12:
+				room[y][x] = strconv.Itoa(10 * (1 + rng.Intn(5)))
+			}
+		}
+	}
+	return room
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// wallValue is the tile value the room files use to mark a wall
+const wallValue = "9001"
+
+// tile is a single position in the room, x is the column and y is the row
+type tile struct {
+	x, y int
+}
+
+// String formats the tile the same way AStar formats its path nodes
+func (t tile) String() string {
+	return fmt.Sprintf("(%d,%d)", t.x, t.y)
+}
+
+// parseTile reads a path node like "(2,3)" back into a tile
+func parseTile(node string) (tile, error) {
+	parts := strings.Split(strings.Trim(node, "()"), ",")
+	if len(parts) != 2 {
+		return tile{}, fmt.Errorf("invalid node format %q", node)
+	}
+	x, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return tile{}, err
+	}
+	y, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return tile{}, err
+	}
+	return tile{x, y}, nil
+}
+
+// directions4 are the four moves the cleaner can make, in the same order AStar tries them
+var directions4 = []tile{{0, -1}, {0, 1}, {-1, 0}, {1, 0}}
+
+func inBounds(room [][]string, x, y int) bool {
+	return y >= 0 && y < len(room) && x >= 0 && x < len(room[y])
+}
+
+// isWall reports whether the tile cannot be entered, anything outside the room counts as a wall
+func isWall(room [][]string, x, y int) bool {
+	if !inBounds(room, x, y) {
+		return true
+	}
+	return strings.TrimSpace(room[y][x]) == wallValue
+}
+
+// tileDirt returns the dirt on a tile, walls and unreadable tiles count as clean
+func tileDirt(room [][]string, x, y int) int {
+	if isWall(room, x, y) {
+		return 0
+	}
+	value, err := strconv.Atoi(strings.TrimSpace(room[y][x]))
+	if err != nil || value < 0 {
+		return 0
+	}
+	return value
+}
+
+// openNeighbors returns the tiles next to t that the cleaner could move into
+func openNeighbors(room [][]string, t tile) []tile {
+	var result []tile
+	for _, d := range directions4 {
+		n := tile{t.x + d.x, t.y + d.y}
+		if !isBlocked(room, n.x, n.y) {
+			result = append(result, n)
+		}
+	}
+	return result
+}
+
+// distanceMap runs a breadth first search from start and returns the number of moves needed
+// to reach every tile, -1 marks tiles that can't be reached
+func distanceMap(room [][]string, start tile) [][]int {
+	dist := make([][]int, len(room))
+	for y := range room {
+		dist[y] = make([]int, len(room[y]))
+		for x := range dist[y] {
+			dist[y][x] = -1
+		}
+	}
+	if isBlocked(room, start.x, start.y) {
+		return dist
+	}
+
+	dist[start.y][start.x] = 0
+	queue := []tile{start}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, n := range openNeighbors(room, current) {
+			if dist[n.y][n.x] == -1 {
+				dist[n.y][n.x] = dist[current.y][current.x] + 1
+				queue = append(queue, n)
+			}
+		}
+	}
+	return dist
+}
+
+// reachableAny reports whether any of the targets can be reached from start
+func reachableAny(room [][]string, start tile, targets []tile) bool {
+	dist := distanceMap(room, start)
+	for _, t := range targets {
+		if inBounds(room, t.x, t.y) && dist[t.y][t.x] >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// copyRoom makes a deep copy of the room so simulations can vacuum without touching the original
+func copyRoom(room [][]string) [][]string {
+	newRoom := make([][]string, len(room))
+	for y := range room {
+		newRoom[y] = make([]string, len(room[y]))
+		copy(newRoom[y], room[y])
+	}
+	return newRoom
+}
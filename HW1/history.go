@@ -0,0 +1,185 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+)
+
+// dirtHistory is what the cleaner learned about where dirt shows up, one entry per room.
+// Rooms are told apart by their walls, the dirt changes from run to run.
+type dirtHistory struct {
+	Rooms map[string]*roomHistory `json:"rooms"`
+}
+
+type roomHistory struct {
+	Width  int            `json:"width"`
+	Height int            `json:"height"`
+	Runs   int            `json:"runs"`
+	Tiles  []*tileHistory `json:"tiles"`
+}
+
+// tileHistory counts the runs in which a tile was looked at and how often it was dirty
+type tileHistory struct {
+	X        int `json:"x"`
+	Y        int `json:"y"`
+	Observed int `json:"observed"`
+	Dirty    int `json:"dirty"`
+	Dirt     int `json:"dirt"` // summed over the runs it was dirty
+}
+
+// roomIdentity hashes the size and the wall layout of a room
+func roomIdentity(room [][]string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d\n", len(room))
+	for y := range room {
+		for x := range room[y] {
+			if isWall(room, x, y) {
+				sb.WriteByte('#')
+			} else {
+				sb.WriteByte('.')
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	sum := sha256.Sum256([]byte(sb.String()))
+	return hex.EncodeToString(sum[:8])
+}
+
+// readHistory loads the history file, a missing file is an empty history
+func readHistory(filePath string) (*dirtHistory, error) {
+	history := &dirtHistory{Rooms: make(map[string]*roomHistory)}
+	data, err := os.ReadFile(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, history); err != nil {
+		return nil, fmt.Errorf("history file %s: %w", filePath, err)
+	}
+	if history.Rooms == nil {
+		history.Rooms = make(map[string]*roomHistory)
+	}
+	return history, nil
+}
+
+func (h *dirtHistory) write(filePath string) error {
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, data, 0644)
+}
+
+func (r *roomHistory) tile(t tile) *tileHistory {
+	for _, th := range r.Tiles {
+		if th.X == t.x && th.Y == t.y {
+			return th
+		}
+	}
+	return nil
+}
+
+// record adds one run, observed maps every tile the cleaner knows about to the dirt it found there
+func (h *dirtHistory) record(id string, room [][]string, observed map[tile]int) {
+	r, ok := h.Rooms[id]
+	if !ok {
+		r = &roomHistory{Height: len(room)}
+		if len(room) > 0 {
+			r.Width = len(room[0])
+		}
+		h.Rooms[id] = r
+	}
+	r.Runs++
+
+	for t, dirt := range observed {
+		th := r.tile(t)
+		if th == nil {
+			th = &tileHistory{X: t.x, Y: t.y}
+			r.Tiles = append(r.Tiles, th)
+		}
+		th.Observed++
+		if dirt > 0 {
+			th.Dirty++
+			th.Dirt += dirt
+		}
+	}
+	sort.Slice(r.Tiles, func(i, j int) bool {
+		return r.Tiles[i].Y < r.Tiles[j].Y || (r.Tiles[i].Y == r.Tiles[j].Y && r.Tiles[i].X < r.Tiles[j].X)
+	})
+}
+
+// prior turns the counts into a starting belief. With no runs it gives the usual prior,
+// every run pulls it towards how often the tile was actually dirty.
+func (r *roomHistory) prior(t tile) tileBelief {
+	b := tileBelief{dirty: beliefPriorDirty, mean: beliefPriorMean, variance: beliefPriorSD * beliefPriorSD}
+	if r == nil {
+		return b
+	}
+	th := r.tile(t)
+	if th == nil {
+		return b
+	}
+	b.dirty = (float64(th.Dirty) + 2*beliefPriorDirty) / float64(th.Observed+2)
+	if th.Dirty > 0 {
+		b.mean = float64(th.Dirt) / float64(th.Dirty)
+	}
+	return b
+}
+
+// initialDirt is what a run with full knowledge of the room finds, the dirt on every floor tile
+func initialDirt(room [][]string) map[tile]int {
+	observed := make(map[tile]int)
+	for y := range room {
+		for x := range room[y] {
+			if !isWall(room, x, y) {
+				observed[tile{x, y}] = max(tileDirt(room, x, y), 0)
+			}
+		}
+	}
+	return observed
+}
+
+// print shows how often each tile was dirty as a percentage, walls as ## and unseen tiles as ..
+func (r *roomHistory) print(id string, room [][]string) {
+	if r == nil {
+		fmt.Println("Nothing learned yet for room", id)
+		return
+	}
+	fmt.Printf("Dirt history for room %s (%dx%d), %d runs:\n", id, r.Width, r.Height, r.Runs)
+	for y := range room {
+		var sb strings.Builder
+		for x := range room[y] {
+			th := r.tile(tile{x, y})
+			switch {
+			case isWall(room, x, y):
+				sb.WriteString("  ##")
+			case th == nil || th.Observed == 0:
+				sb.WriteString("  ..")
+			default:
+				fmt.Fprintf(&sb, "%4d", 100*th.Dirty/th.Observed)
+			}
+		}
+		fmt.Println(sb.String())
+	}
+
+	hotspots := append([]*tileHistory(nil), r.Tiles...)
+	sort.SliceStable(hotspots, func(i, j int) bool {
+		return hotspots[i].Dirty*hotspots[j].Observed > hotspots[j].Dirty*hotspots[i].Observed
+	})
+	fmt.Println("Hotspots:")
+	for i, th := range hotspots {
+		if i == 5 || th.Dirty == 0 {
+			break
+		}
+		fmt.Printf("  (%d,%d) dirty in %d of %d runs, %d dirt on average\n", th.X, th.Y, th.Dirty, th.Observed, th.Dirt/th.Dirty)
+	}
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// houseRoom is one room grid of a house together with what the cleaner did in it
+type houseRoom struct {
+	name  string
+	grid  [][]string
+	stats roomStats
+}
+
+// roomStats are the per room numbers printed in the house report
+type roomStats struct {
+	energy       int
+	dirt         int
+	tilesCleaned int
+	moves        int
+}
+
+// door connects a tile in one room with a tile in another, going through it is a single move.
+// Open doors can always be used, closed doors only when opened for the run and locked doors never.
+type door struct {
+	name     string
+	from, to int // room indexes
+	fromTile tile
+	toTile   tile
+	state    string
+}
+
+type house struct {
+	rooms []*houseRoom
+	doors []door
+}
+
+// openDoors are closed doors the user opened for this run
+var openDoors map[string]bool
+
+func (d door) passable() bool {
+	return d.state == "open" || (d.state == "closed" && openDoors[d.name])
+}
+
+// side returns the door end in room r and the end in the other room
+func (d door) side(r int) (tile, int, tile) {
+	if d.from == r {
+		return d.fromTile, d.to, d.toTile
+	}
+	return d.toTile, d.from, d.fromTile
+}
+
+type jsonHouse struct {
+	Start *struct {
+		Room string `json:"room"`
+		X    int    `json:"x"`
+		Y    int    `json:"y"`
+	} `json:"start"`
+	Rooms []struct {
+		Name string  `json:"name"`
+		File string  `json:"file"`
+		Grid [][]int `json:"grid"`
+	} `json:"rooms"`
+	Doors []struct {
+		Name  string `json:"name"`
+		From  string `json:"from"`
+		FromX int    `json:"from_x"`
+		FromY int    `json:"from_y"`
+		To    string `json:"to"`
+		ToX   int    `json:"to_x"`
+		ToY   int    `json:"to_y"`
+		State string `json:"state"`
+	} `json:"doors"`
+}
+
+// loadHouse reads a house description, any other room file loads as a house with a single room.
+// The cleaner takes its settings from the first room file and starts in the first room
+// unless the house names a start.
+func (c *Cleaner) loadHouse(filePath string) (*house, int) {
+	var description jsonHouse
+	if strings.ToLower(filepath.Ext(filePath)) == ".json" {
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			log.Fatal("Unable to read input file "+filePath, err)
+		}
+		if err := json.Unmarshal(data, &description); err != nil {
+			log.Fatal("Error reading json data ", err)
+		}
+	}
+	if len(description.Rooms) == 0 {
+		grid := c.loadRoom(filePath)
+		name := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+		return &house{rooms: []*houseRoom{{name: name, grid: grid}}}, 0
+	}
+
+	h := &house{}
+	index := make(map[string]int)
+	settings := *c
+	for i, r := range description.Rooms {
+		room := &houseRoom{name: r.Name}
+		if r.File != "" {
+			// Room files carry their own cleaner settings, only the first one is kept
+			loader := *c
+			room.grid = loader.loadRoom(filepath.Join(filepath.Dir(filePath), r.File))
+			if i == 0 {
+				settings = loader
+			}
+		} else {
+			room.grid = make([][]string, len(r.Grid))
+			for y, row := range r.Grid {
+				room.grid[y] = make([]string, len(row))
+				for x, value := range row {
+					room.grid[y][x] = strconv.Itoa(value)
+				}
+			}
+		}
+		index[r.Name] = i
+		h.rooms = append(h.rooms, room)
+	}
+	c.locationX, c.locationY = settings.locationX, settings.locationY
+	c.battery, c.movementEnergy, c.vacuumEnergy = settings.battery, settings.movementEnergy, settings.vacuumEnergy
+
+	for _, d := range description.Doors {
+		from, okFrom := index[d.From]
+		to, okTo := index[d.To]
+		if !okFrom || !okTo {
+			fmt.Println("Skipping door", d.Name, "between unknown rooms", d.From, "and", d.To)
+			continue
+		}
+		state := d.State
+		if state == "" {
+			state = "open"
+		}
+		h.doors = append(h.doors, door{
+			name: d.Name, from: from, to: to,
+			fromTile: tile{d.FromX, d.FromY}, toTile: tile{d.ToX, d.ToY},
+			state: state,
+		})
+	}
+
+	start := 0
+	if description.Start != nil {
+		if i, ok := index[description.Start.Room]; ok {
+			start = i
+			c.locationX, c.locationY = description.Start.X, description.Start.Y
+		} else {
+			fmt.Println("Unknown start room", description.Start.Room, "starting in", h.rooms[0].name)
+		}
+	}
+	return h, start
+}
+
+// houseRoute is the cheapest way found into a room, as the doors to walk through
+type houseRoute struct {
+	cost  int
+	entry tile
+	doors []door
+}
+
+// planRooms searches the room graph from the cleaner's position. Every door end is a node,
+// the cost to walk between two door ends of the same room comes from the tile grid.
+func (h *house) planRooms(current int, position tile) map[int]houseRoute {
+	type node struct {
+		room int
+		at   tile
+	}
+	routes := map[int]houseRoute{current: {entry: position}}
+	best := map[node]houseRoute{{current, position}: {entry: position}}
+	done := make(map[node]bool)
+	for {
+		// Pick the cheapest unfinished node, the graph only has a couple of nodes per door
+		var next node
+		found := false
+		for n, r := range best {
+			if !done[n] && (!found || r.cost < best[next].cost) {
+				next, found = n, true
+			}
+		}
+		if !found {
+			return routes
+		}
+		done[next] = true
+		route := best[next]
+
+		dist := distanceMap(h.rooms[next.room].grid, next.at)
+		for _, d := range h.doors {
+			if !d.passable() || (d.from != next.room && d.to != next.room) {
+				continue
+			}
+			here, other, there := d.side(next.room)
+			if !inBounds(h.rooms[next.room].grid, here.x, here.y) || dist[here.y][here.x] < 0 || isBlocked(h.rooms[other].grid, there.x, there.y) {
+				continue
+			}
+			n := node{other, there}
+			cost := route.cost + dist[here.y][here.x] + 1
+			if old, ok := best[n]; ok && old.cost <= cost {
+				continue
+			}
+			doors := append(append([]door(nil), route.doors...), d)
+			best[n] = houseRoute{cost: cost, entry: there, doors: doors}
+			if old, ok := routes[other]; !ok || cost < old.cost {
+				routes[other] = best[n]
+			}
+		}
+	}
+}
+
+// crossDoor moves the cleaner through a door into the other room, it costs one move
+func (c *Cleaner) crossDoor(h *house, current int, d door) (int, bool) {
+	here, other, there := d.side(current)
+	if c.locationX != here.x || c.locationY != here.y || c.battery < c.cost(c.movementEnergy) {
+		fmt.Println("Cannot go through door", d.name)
+		return current, false
+	}
+	c.spend(c.movementEnergy)
+	c.ticks++
+	c.locationX, c.locationY = there.x, there.y
+	c.trail = nil
+	c.recordTrail()
+	fmt.Println("Went through door", d.name, "into", h.rooms[other].name)
+	return other, true
+}
+
+// walkTo follows the shortest path inside the room, vacuuming on the way like the AStar loop.
+// It returns the tiles it walked and whether it arrived.
+func (c *Cleaner) walkTo(room [][]string, target tile) ([]tile, bool) {
+	var walked []tile
+	for _, next := range shortestPath(room, tile{c.locationX, c.locationY}, target) {
+		c.moveSomewhere(next.String(), room)
+		if c.locationX != next.x || c.locationY != next.y {
+			return walked, false
+		}
+		walked = append(walked, next)
+		c.decideToClean(room)
+	}
+	return walked, c.locationX == target.x && c.locationY == target.y
+}
+
+// cleanHouse keeps going to the room with the dirtiest reachable tile, planning the doors first
+// and then the tiles inside each room with the selected planner
+func (c *Cleaner) cleanHouse(h *house, current int) []string {
+	totalPath := []string{fmt.Sprintf("%s%v", h.rooms[current].name, tile{c.locationX, c.locationY})}
+	c.recordTrail()
+	for c.battery > 0 {
+		// Choose the room holding the most dirt on one tile, the closer room wins a tie
+		routes := h.planRooms(current, tile{c.locationX, c.locationY})
+		target, targetDirt := -1, 0
+		for r := range h.rooms {
+			route, ok := routes[r]
+			if !ok {
+				continue
+			}
+			dirt := 0
+			dist := distanceMap(h.rooms[r].grid, route.entry)
+			for _, t := range dirtiestTiles(h.rooms[r].grid) {
+				if dist[t.y][t.x] >= 0 {
+					dirt = tileDirt(h.rooms[r].grid, t.x, t.y)
+				}
+			}
+			if dirt > targetDirt || (dirt == targetDirt && dirt > 0 && route.cost < routes[target].cost) {
+				target, targetDirt = r, dirt
+			}
+		}
+		if target == -1 {
+			fmt.Println("No more paths to dirty tiles in the house.")
+			break
+		}
+
+		before := *c
+		room := h.rooms[current]
+		progress := false
+		if target == current {
+			// The dirtiest tile may be the one the cleaner stands on, the path to it is then empty
+			c.decideToClean(room.grid)
+			path := planners[plannerName](c.locationX, c.locationY, room.grid)
+			for _, node := range path {
+				if node == (tile{c.locationX, c.locationY}).String() {
+					continue
+				}
+				c.moveSomewhere(node, room.grid)
+				c.decideToClean(room.grid)
+				totalPath = append(totalPath, room.name+node)
+			}
+			progress = c.locationX != before.locationX || c.locationY != before.locationY || c.dirtVolume != before.dirtVolume
+		} else {
+			for _, d := range routes[target].doors {
+				here, _, _ := d.side(current)
+				walked, arrived := c.walkTo(h.rooms[current].grid, here)
+				for _, t := range walked {
+					totalPath = append(totalPath, h.rooms[current].name+t.String())
+				}
+				if !arrived {
+					break
+				}
+				h.rooms[current].stats.add(before, *c)
+				before = *c
+				next, ok := c.crossDoor(h, current, d)
+				if !ok {
+					break
+				}
+				h.rooms[current].stats.add(before, *c)
+				before = *c
+				current = next
+				totalPath = append(totalPath, h.rooms[current].name+tile{c.locationX, c.locationY}.String())
+				c.decideToClean(h.rooms[current].grid)
+				progress = true
+			}
+		}
+		h.rooms[current].stats.add(before, *c)
+		if !progress {
+			if c.battery < c.cost(c.movementEnergy) {
+				fmt.Println("Not enough battery to reach the dirtiest tiles.")
+			} else {
+				fmt.Println("No more progress towards the dirtiest tiles in the house.")
+			}
+			break
+		}
+	}
+	return totalPath
+}
+
+// add charges everything the cleaner did since before to the room
+func (s *roomStats) add(before, after Cleaner) {
+	s.energy += before.battery - after.battery
+	s.dirt += after.dirtVolume - before.dirtVolume
+	s.tilesCleaned += after.tilesCleaned - before.tilesCleaned
+	s.moves += after.ticks - before.ticks - (after.tilesCleaned - before.tilesCleaned)
+}
+
+func (h *house) print() {
+	fmt.Println("House report:")
+	fmt.Printf("%-12s %8s %8s %8s %8s %10s\n", "room", "energy", "moves", "dirt", "cleaned", "dirt left")
+	for _, r := range h.rooms {
+		left := 0
+		for y := range r.grid {
+			for x := range r.grid[y] {
+				left += tileDirt(r.grid, x, y)
+			}
+		}
+		fmt.Printf("%-12s %8d %8d %8d %8d %10d\n", r.name, r.stats.energy, r.stats.moves, r.stats.dirt, r.stats.tilesCleaned, left)
+	}
+	for _, d := range h.doors {
+		state := d.state
+		if d.state == "closed" && d.passable() {
+			state = "closed, opened for this run"
+		}
+		fmt.Printf("Door %s between %s and %s is %s\n", d.name, h.rooms[d.from].name, h.rooms[d.to].name, state)
+	}
+}
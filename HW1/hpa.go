@@ -0,0 +1,546 @@
+package main
+
+import (
+	"container/heap"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// hpaClusterSize is the width and height of one cluster in tiles
+const hpaClusterSize = 10
+
+type hpaEdge struct {
+	to   tile
+	cost int
+}
+
+// hpaGraph is the abstract graph used by HPA* (Botea, Mueller and Schaeffer, 2004).
+// The room is cut into square clusters, tiles on both sides of a gap in a cluster border become
+// entrance nodes, and nodes inside the same cluster are joined by their precomputed distance.
+type hpaGraph struct {
+	room      [][]string
+	walls     [][]bool          // wall layout the graph was built for, used to spot changes
+	borders   map[[3]int][]tile // entrance pairs per border, key is cluster x, cluster y and 0 for east or 1 for south
+	intra     map[[2]int][]hpaIntraEdge
+	edges     map[tile][]hpaEdge
+	rebuilt   int // clusters rebuilt because walls changed
+	edits     int // roomEdits when the graph last looked at the room
+	expanded  int // abstract and local nodes expanded by the last query
+	clustersX int
+	clustersY int
+}
+
+// hpaIntraEdge is an intra cluster edge between two of its entrance nodes
+type hpaIntraEdge struct {
+	from, to tile
+	cost     int
+}
+
+func newHPAGraph(room [][]string) *hpaGraph {
+	h := &hpaGraph{
+		room:    room,
+		borders: make(map[[3]int][]tile),
+		intra:   make(map[[2]int][]hpaIntraEdge),
+	}
+	width := 0
+	for _, row := range room {
+		width = max(width, len(row))
+	}
+	h.clustersX = (width + hpaClusterSize - 1) / hpaClusterSize
+	h.clustersY = (len(room) + hpaClusterSize - 1) / hpaClusterSize
+	h.walls = wallLayout(room)
+
+	for cy := 0; cy < h.clustersY; cy++ {
+		for cx := 0; cx < h.clustersX; cx++ {
+			h.buildBorder(cx, cy, 0)
+			h.buildBorder(cx, cy, 1)
+		}
+	}
+	for cy := 0; cy < h.clustersY; cy++ {
+		for cx := 0; cx < h.clustersX; cx++ {
+			h.buildIntra(cx, cy)
+		}
+	}
+	h.link()
+	return h
+}
+
+func wallLayout(room [][]string) [][]bool {
+	walls := make([][]bool, len(room))
+	for y := range room {
+		walls[y] = make([]bool, len(room[y]))
+		for x := range room[y] {
+			walls[y][x] = isBlocked(room, x, y)
+		}
+	}
+	return walls
+}
+
+// clusterBounds returns the top left and bottom right tile of a cluster
+func (h *hpaGraph) clusterBounds(cx, cy int) (tile, tile) {
+	return tile{cx * hpaClusterSize, cy * hpaClusterSize},
+		tile{(cx+1)*hpaClusterSize - 1, (cy+1)*hpaClusterSize - 1}
+}
+
+func (h *hpaGraph) clusterOf(t tile) [2]int {
+	return [2]int{t.x / hpaClusterSize, t.y / hpaClusterSize}
+}
+
+// buildBorder finds the entrances on the east (side 0) or south (side 1) border of a cluster.
+// Every run of open tile pairs across the border gets one entrance in its middle,
+// long runs get one at each end instead.
+func (h *hpaGraph) buildBorder(cx, cy, side int) {
+	key := [3]int{cx, cy, side}
+	delete(h.borders, key)
+	if (side == 0 && cx+1 >= h.clustersX) || (side == 1 && cy+1 >= h.clustersY) {
+		return
+	}
+
+	topLeft, bottomRight := h.clusterBounds(cx, cy)
+	var pairs []tile
+	var run [][2]tile
+	flush := func() {
+		switch {
+		case len(run) == 0:
+		case len(run) < 6:
+			pairs = append(pairs, run[len(run)/2][0], run[len(run)/2][1])
+		default:
+			pairs = append(pairs, run[0][0], run[0][1], run[len(run)-1][0], run[len(run)-1][1])
+		}
+		run = nil
+	}
+	for i := 0; i < hpaClusterSize; i++ {
+		var a, b tile
+		if side == 0 {
+			a = tile{bottomRight.x, topLeft.y + i}
+			b = tile{bottomRight.x + 1, topLeft.y + i}
+		} else {
+			a = tile{topLeft.x + i, bottomRight.y}
+			b = tile{topLeft.x + i, bottomRight.y + 1}
+		}
+		if isBlocked(h.room, a.x, a.y) || isBlocked(h.room, b.x, b.y) {
+			flush()
+			continue
+		}
+		run = append(run, [2]tile{a, b})
+	}
+	flush()
+	h.borders[key] = pairs
+}
+
+// clusterNodes returns the entrance nodes lying inside a cluster
+func (h *hpaGraph) clusterNodes(cx, cy int) []tile {
+	var nodes []tile
+	seen := make(map[tile]bool)
+	add := func(t tile) {
+		if h.clusterOf(t) == [2]int{cx, cy} && !seen[t] {
+			seen[t] = true
+			nodes = append(nodes, t)
+		}
+	}
+	for _, key := range [][3]int{{cx, cy, 0}, {cx, cy, 1}, {cx - 1, cy, 0}, {cx, cy - 1, 1}} {
+		for _, t := range h.borders[key] {
+			add(t)
+		}
+	}
+	return nodes
+}
+
+// clusterDistances holds breadth first search distances inside one cluster, -1 is unreachable
+type clusterDistances struct {
+	origin tile
+	dist   [hpaClusterSize * hpaClusterSize]int
+}
+
+func (c *clusterDistances) get(t tile) (int, bool) {
+	x, y := t.x-c.origin.x, t.y-c.origin.y
+	if x < 0 || y < 0 || x >= hpaClusterSize || y >= hpaClusterSize {
+		return 0, false
+	}
+	d := c.dist[y*hpaClusterSize+x]
+	return d, d >= 0
+}
+
+// localDistances runs a breadth first search from start that stays inside one cluster
+func (h *hpaGraph) localDistances(start tile) *clusterDistances {
+	cluster := h.clusterOf(start)
+	result := &clusterDistances{origin: tile{cluster[0] * hpaClusterSize, cluster[1] * hpaClusterSize}}
+	for i := range result.dist {
+		result.dist[i] = -1
+	}
+	set := func(t tile, d int) {
+		result.dist[(t.y-result.origin.y)*hpaClusterSize+t.x-result.origin.x] = d
+	}
+	set(start, 0)
+	queue := []tile{start}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		h.expanded++
+		d, _ := result.get(current)
+		for _, n := range openNeighbors(h.room, current) {
+			if _, seen := result.get(n); seen || h.clusterOf(n) != cluster {
+				continue
+			}
+			set(n, d+1)
+			queue = append(queue, n)
+		}
+	}
+	return result
+}
+
+// buildIntra precomputes the distance between every pair of entrance nodes in a cluster
+func (h *hpaGraph) buildIntra(cx, cy int) {
+	key := [2]int{cx, cy}
+	delete(h.intra, key)
+	nodes := h.clusterNodes(cx, cy)
+	for i, from := range nodes {
+		dist := h.localDistances(from)
+		for _, to := range nodes[i+1:] {
+			if d, ok := dist.get(to); ok {
+				h.intra[key] = append(h.intra[key], hpaIntraEdge{from, to, d})
+			}
+		}
+	}
+}
+
+// link rebuilds the adjacency lists from the border and cluster data
+func (h *hpaGraph) link() {
+	h.edges = make(map[tile][]hpaEdge)
+	for _, pairs := range h.borders {
+		for i := 0; i+1 < len(pairs); i += 2 {
+			h.edges[pairs[i]] = append(h.edges[pairs[i]], hpaEdge{pairs[i+1], 1})
+			h.edges[pairs[i+1]] = append(h.edges[pairs[i+1]], hpaEdge{pairs[i], 1})
+		}
+	}
+	for _, edges := range h.intra {
+		for _, e := range edges {
+			h.edges[e.from] = append(h.edges[e.from], hpaEdge{e.to, e.cost})
+			h.edges[e.to] = append(h.edges[e.to], hpaEdge{e.from, e.cost})
+		}
+	}
+}
+
+// update rebuilds only the clusters whose walls changed since the graph was built
+func (h *hpaGraph) update() {
+	current := wallLayout(h.room)
+	dirty := make(map[[2]int]bool)
+	for y := range current {
+		for x := range current[y] {
+			if y >= len(h.walls) || x >= len(h.walls[y]) || current[y][x] != h.walls[y][x] {
+				dirty[h.clusterOf(tile{x, y})] = true
+			}
+		}
+	}
+	h.walls = current
+	if len(dirty) == 0 {
+		return
+	}
+
+	// A changed cluster moves the entrances on its four borders, which changes its neighbours too
+	affected := make(map[[2]int]bool)
+	for cluster := range dirty {
+		cx, cy := cluster[0], cluster[1]
+		h.buildBorder(cx, cy, 0)
+		h.buildBorder(cx, cy, 1)
+		h.buildBorder(cx-1, cy, 0)
+		h.buildBorder(cx, cy-1, 1)
+		for _, c := range [][2]int{{cx, cy}, {cx - 1, cy}, {cx + 1, cy}, {cx, cy - 1}, {cx, cy + 1}} {
+			affected[c] = true
+		}
+	}
+	for cluster := range affected {
+		if cluster[0] >= 0 && cluster[1] >= 0 && cluster[0] < h.clustersX && cluster[1] < h.clustersY {
+			h.buildIntra(cluster[0], cluster[1])
+			h.rebuilt++
+		}
+	}
+	h.link()
+}
+
+type hpaItem struct {
+	at   tile
+	cost int // estimated total cost through this node
+}
+
+type hpaQueue []hpaItem
+
+func (q hpaQueue) Len() int            { return len(q) }
+func (q hpaQueue) Less(i, j int) bool  { return q[i].cost < q[j].cost }
+func (q hpaQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *hpaQueue) Push(x interface{}) { *q = append(*q, x.(hpaItem)) }
+func (q *hpaQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// findPath searches the abstract graph from start to the closest goal and refines the result
+// into single tile steps. The returned path starts with start, nil means no goal is reachable.
+func (h *hpaGraph) findPath(start tile, goals []tile) []tile {
+	h.expanded = 0
+	if isBlocked(h.room, start.x, start.y) {
+		return nil
+	}
+
+	// Temporary edges connecting the start and goals to the entrances of their clusters
+	extra := make(map[tile][]hpaEdge)
+	startCluster := h.clusterOf(start)
+	startDist := h.localDistances(start)
+	for _, node := range h.clusterNodes(startCluster[0], startCluster[1]) {
+		if d, ok := startDist.get(node); ok {
+			extra[start] = append(extra[start], hpaEdge{node, d})
+		}
+	}
+	isGoal := make(map[tile]bool)
+	for _, goal := range goals {
+		if isBlocked(h.room, goal.x, goal.y) {
+			continue
+		}
+		isGoal[goal] = true
+		if d, ok := startDist.get(goal); ok {
+			extra[start] = append(extra[start], hpaEdge{goal, d})
+		}
+		goalCluster := h.clusterOf(goal)
+		goalDist := h.localDistances(goal)
+		for _, node := range h.clusterNodes(goalCluster[0], goalCluster[1]) {
+			if d, ok := goalDist.get(node); ok {
+				extra[node] = append(extra[node], hpaEdge{goal, d})
+			}
+		}
+	}
+	if isGoal[start] {
+		return []tile{start}
+	}
+
+	// A* over the abstract graph, guided by the Manhattan distance to the closest goal
+	heuristic := func(t tile) int {
+		best := -1
+		for goal := range isGoal {
+			if d := abs(t.x-goal.x) + abs(t.y-goal.y); best == -1 || d < best {
+				best = d
+			}
+		}
+		return best
+	}
+	dist := map[tile]int{start: 0}
+	parent := make(map[tile]tile)
+	queue := &hpaQueue{{start, heuristic(start)}}
+	var reached *tile
+	for queue.Len() > 0 {
+		item := heap.Pop(queue).(hpaItem)
+		g := dist[item.at]
+		if item.cost > g+heuristic(item.at) {
+			continue
+		}
+		h.expanded++
+		if isGoal[item.at] {
+			reached = &item.at
+			break
+		}
+		for _, list := range [][]hpaEdge{h.edges[item.at], extra[item.at]} {
+			for _, e := range list {
+				if d, ok := dist[e.to]; !ok || g+e.cost < d {
+					dist[e.to] = g + e.cost
+					parent[e.to] = item.at
+					heap.Push(queue, hpaItem{e.to, g + e.cost + heuristic(e.to)})
+				}
+			}
+		}
+	}
+	if reached == nil {
+		return nil
+	}
+
+	var abstract []tile
+	for at := *reached; at != start; at = parent[at] {
+		abstract = append([]tile{at}, abstract...)
+	}
+	abstract = append([]tile{start}, abstract...)
+
+	// Refine every abstract hop into tiles, hops between clusters are a single step
+	path := []tile{start}
+	for i := 1; i < len(abstract); i++ {
+		path = append(path, h.refine(abstract[i-1], abstract[i])...)
+	}
+	return path
+}
+
+// refine returns the tiles after from on the shortest path to to, staying inside from's cluster
+func (h *hpaGraph) refine(from, to tile) []tile {
+	if h.clusterOf(from) != h.clusterOf(to) {
+		return []tile{to}
+	}
+	dist := h.localDistances(to)
+	var path []tile
+	for current := from; current != to; {
+		here, _ := dist.get(current)
+		for _, n := range openNeighbors(h.room, current) {
+			if d, ok := dist.get(n); ok && d == here-1 {
+				current = n
+				break
+			}
+		}
+		path = append(path, current)
+	}
+	return path
+}
+
+// hpaCache keeps the abstract graph between calls of HPAStar for the same room
+var hpaCache *hpaGraph
+
+// roomEdits counts the changes the cleaner made to the room, walls moved by events and tiles cleaned,
+// so the cached graph knows when it has to look at the room again
+var roomEdits int
+
+// HPAStar has the same contract as AStar, it returns a path from the start to one of the dirtiest tiles
+// but answers it on the cached abstract graph, only rebuilding the clusters where walls changed
+func HPAStar(startX, startY int, room [][]string) []string {
+	// The abstract graph counts every step the same, avoid zones need the weighted search
+	if activeOverlay.hasPenalties() {
+		return AStar(startX, startY, room)
+	}
+	goals := dirtiestTiles(room)
+	if len(goals) == 0 {
+		return nil
+	}
+	if hpaCache == nil || !sameRoom(hpaCache.room, room) {
+		hpaCache = newHPAGraph(room)
+	} else if hpaCache.edits != roomEdits {
+		hpaCache.update()
+	}
+	hpaCache.edits = roomEdits
+
+	var path []string
+	for _, t := range hpaCache.findPath(tile{startX, startY}, goals) {
+		path = append(path, t.String())
+	}
+	return path
+}
+
+// sameRoom reports whether both grids are the same slice, not just equal contents
+func sameRoom(a, b [][]string) bool {
+	return len(a) == len(b) && len(a) > 0 && &a[0] == &b[0]
+}
+
+type flatNode struct {
+	at tile
+	f  int
+}
+
+type flatQueue []flatNode
+
+func (q flatQueue) Len() int            { return len(q) }
+func (q flatQueue) Less(i, j int) bool  { return q[i].f < q[j].f }
+func (q flatQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *flatQueue) Push(x interface{}) { *q = append(*q, x.(flatNode)) }
+func (q *flatQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// flatAStar is a plain grid A* with a binary heap used as the baseline in benchmarks.
+// It returns the path length in moves, -1 if the goal can't be reached, and the tiles expanded.
+func flatAStar(room [][]string, start, goal tile) (int, int) {
+	g := map[tile]int{start: 0}
+	closed := make(map[tile]bool)
+	queue := &flatQueue{{start, abs(start.x-goal.x) + abs(start.y-goal.y)}}
+	expanded := 0
+	for queue.Len() > 0 {
+		current := heap.Pop(queue).(flatNode).at
+		if closed[current] {
+			continue
+		}
+		closed[current] = true
+		expanded++
+		if current == goal {
+			return g[current], expanded
+		}
+		for _, n := range openNeighbors(room, current) {
+			if d, ok := g[n]; !ok || g[current]+1 < d {
+				g[n] = g[current] + 1
+				heap.Push(queue, flatNode{n, g[n] + abs(n.x-goal.x) + abs(n.y-goal.y)})
+			}
+		}
+	}
+	return -1, expanded
+}
+
+// benchmarkHPA compares flat A* with HPA* on a generated size by size floor plan
+func benchmarkHPA(size int, queries int) {
+	room := generateRoom(size, size, 1)
+	rng := rand.New(rand.NewSource(2))
+	randomFloor := func() tile {
+		for {
+			t := tile{rng.Intn(size), rng.Intn(size)}
+			if !isWall(room, t.x, t.y) {
+				return t
+			}
+		}
+	}
+	pairs := make([][2]tile, queries)
+	for i := range pairs {
+		pairs[i] = [2]tile{randomFloor(), randomFloor()}
+	}
+
+	buildStart := time.Now()
+	graph := newHPAGraph(room)
+	buildTime := time.Since(buildStart)
+
+	var flatTime, hpaTime time.Duration
+	flatExpanded, hpaExpanded, found, longer := 0, 0, 0, 0
+	optimalLength, hpaLength := 0, 0
+	worst := 0.0 // largest extra length of one HPA* path over the optimal one, as a fraction
+	for _, pair := range pairs {
+		begin := time.Now()
+		length, expanded := flatAStar(room, pair[0], pair[1])
+		flatTime += time.Since(begin)
+		flatExpanded += expanded
+
+		begin = time.Now()
+		path := graph.findPath(pair[0], []tile{pair[1]})
+		hpaTime += time.Since(begin)
+		hpaExpanded += graph.expanded
+
+		if length >= 0 && path != nil {
+			found++
+			optimalLength += length
+			hpaLength += len(path) - 1
+			if len(path)-1 > length {
+				longer++
+				if length > 0 {
+					worst = max(worst, float64(len(path)-1-length)/float64(length))
+				}
+			}
+		}
+	}
+
+	// Move a few walls and time the partial rebuild against building from scratch
+	for i := 0; i < 5; i++ {
+		t := randomFloor()
+		room[t.y][t.x] = wallValue
+	}
+	rebuildStart := time.Now()
+	graph.update()
+	rebuildTime := time.Since(rebuildStart)
+
+	fmt.Printf("HPA* benchmark on a %dx%d room with %d queries\n", size, size, queries)
+	fmt.Printf("Abstract graph: %d clusters, %d nodes, built in %v\n", graph.clustersX*graph.clustersY, len(graph.edges), buildTime)
+	fmt.Printf("After 5 wall changes rebuilt %d clusters in %v\n", graph.rebuilt, rebuildTime)
+	fmt.Printf("Flat A*: %v, %d tiles expanded\n", flatTime, flatExpanded)
+	fmt.Printf("HPA*:    %v, %d nodes expanded\n", hpaTime, hpaExpanded)
+	// HPA* trades path length for speed, so the speedup is only shown with what it costs
+	suboptimality := 0.0
+	if optimalLength > 0 {
+		suboptimality = float64(hpaLength-optimalLength) / float64(optimalLength)
+	}
+	if hpaTime > 0 {
+		fmt.Printf("Speedup: %.2fx with paths %.1f%% longer than optimal in total, %.1f%% for the worst path\n",
+			float64(flatTime)/float64(hpaTime), 100*suboptimality, 100*worst)
+	}
+	fmt.Printf("Paths found by both: %d, HPA* path longer than optimal: %d\n", found, longer)
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"container/heap"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Octile step costs for the 8-connected search, a diagonal is roughly sqrt(2) straight steps
+const (
+	straightCost = 10
+	diagonalCost = 14
+)
+
+// jumpPointSearch finds jump points from start to the closest goal on a grid where every step costs
+// the same (Harabor and Grastien, 2011). Instead of adding every neighbour to the open list it jumps
+// in straight lines and only stops where a wall forces a turn, which skips most of the symmetric
+// paths plain A* expands. With diagonal off it uses the 4-connected variant where vertical moves
+// play the role diagonals have in the original algorithm. Diagonal steps never cut wall corners.
+type jumpPointSearch struct {
+	room     [][]string
+	goals    map[tile]bool
+	diagonal bool
+	expanded int
+}
+
+func (j *jumpPointSearch) open(x, y int) bool {
+	return !isBlocked(j.room, x, y)
+}
+
+func (j *jumpPointSearch) heuristic(t tile) int {
+	best := -1
+	for goal := range j.goals {
+		dx, dy := abs(t.x-goal.x), abs(t.y-goal.y)
+		h := straightCost * (dx + dy)
+		if j.diagonal {
+			h = straightCost*max(dx, dy) + (diagonalCost-straightCost)*min(dx, dy)
+		}
+		if best == -1 || h < best {
+			best = h
+		}
+	}
+	return best
+}
+
+// distance between two jump points on the same straight or diagonal line
+func (j *jumpPointSearch) distance(a, b tile) int {
+	dx, dy := abs(a.x-b.x), abs(a.y-b.y)
+	if j.diagonal {
+		return straightCost*max(dx, dy) + (diagonalCost-straightCost)*min(dx, dy)
+	}
+	return straightCost * (dx + dy)
+}
+
+func sign(v int) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
+
+// jump moves from (x, y) in direction (dx, dy) until it finds a jump point, nil if it hits a wall
+func (j *jumpPointSearch) jump(x, y, dx, dy int) *tile {
+	for {
+		x, y = x+dx, y+dy
+		if !j.open(x, y) {
+			return nil
+		}
+		if j.goals[tile{x, y}] {
+			return &tile{x, y}
+		}
+
+		if j.diagonal {
+			switch {
+			case dx != 0 && dy != 0:
+				if j.jump(x, y, dx, 0) != nil || j.jump(x, y, 0, dy) != nil {
+					return &tile{x, y}
+				}
+				// No corner cutting, both sides have to be open to keep going diagonally
+				if !j.open(x+dx, y) || !j.open(x, y+dy) {
+					return nil
+				}
+			case dx != 0:
+				if (j.open(x, y-1) && !j.open(x-dx, y-1)) || (j.open(x, y+1) && !j.open(x-dx, y+1)) {
+					return &tile{x, y}
+				}
+			default:
+				if (j.open(x-1, y) && !j.open(x-1, y-dy)) || (j.open(x+1, y) && !j.open(x+1, y-dy)) {
+					return &tile{x, y}
+				}
+			}
+			continue
+		}
+
+		// 4-connected: horizontal jumps stop where a wall behind opens up above or below,
+		// vertical jumps also stop wherever a horizontal jump would find something
+		if dx != 0 {
+			if (j.open(x, y-1) && !j.open(x-dx, y-1)) || (j.open(x, y+1) && !j.open(x-dx, y+1)) {
+				return &tile{x, y}
+			}
+			continue
+		}
+		if (j.open(x-1, y) && !j.open(x-1, y-dy)) || (j.open(x+1, y) && !j.open(x+1, y-dy)) {
+			return &tile{x, y}
+		}
+		if j.jump(x, y, -1, 0) != nil || j.jump(x, y, 1, 0) != nil {
+			return &tile{x, y}
+		}
+	}
+}
+
+// directions returns the directions worth searching from a jump point reached from parent
+func (j *jumpPointSearch) directions(at tile, parent *tile) [][2]int {
+	if parent == nil {
+		result := [][2]int{{0, -1}, {0, 1}, {-1, 0}, {1, 0}}
+		if j.diagonal {
+			result = append(result, [2]int{-1, -1}, [2]int{1, -1}, [2]int{-1, 1}, [2]int{1, 1})
+		}
+		return result
+	}
+
+	x, y := at.x, at.y
+	dx, dy := sign(x-parent.x), sign(y-parent.y)
+	var result [][2]int
+	if !j.diagonal {
+		if dx != 0 {
+			result = append(result, [2]int{dx, 0})
+			for _, side := range []int{-1, 1} {
+				if j.open(x, y+side) && !j.open(x-dx, y+side) {
+					result = append(result, [2]int{0, side})
+				}
+			}
+			return result
+		}
+		return [][2]int{{0, dy}, {-1, 0}, {1, 0}}
+	}
+
+	switch {
+	case dx != 0 && dy != 0:
+		result = append(result, [2]int{0, dy}, [2]int{dx, 0}, [2]int{dx, dy})
+	case dx != 0:
+		result = append(result, [2]int{dx, 0})
+		for _, side := range []int{-1, 1} {
+			if j.open(x, y+side) {
+				result = append(result, [2]int{0, side}, [2]int{dx, side})
+			}
+		}
+	default:
+		result = append(result, [2]int{0, dy})
+		for _, side := range []int{-1, 1} {
+			if j.open(x+side, y) {
+				result = append(result, [2]int{side, 0}, [2]int{side, dy})
+			}
+		}
+	}
+	return result
+}
+
+type jpsNode struct {
+	at     tile
+	g, f   int
+	parent *jpsNode
+}
+
+type jpsQueue []*jpsNode
+
+func (q jpsQueue) Len() int            { return len(q) }
+func (q jpsQueue) Less(i, j int) bool  { return q[i].f < q[j].f }
+func (q jpsQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *jpsQueue) Push(x interface{}) { *q = append(*q, x.(*jpsNode)) }
+func (q *jpsQueue) Pop() interface{} {
+	old := *q
+	node := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return node
+}
+
+// search returns the jump points from start to the closest goal and the path cost
+func (j *jumpPointSearch) search(start tile) ([]tile, int) {
+	j.expanded = 0
+	if !j.open(start.x, start.y) {
+		return nil, -1
+	}
+	best := map[tile]int{start: 0}
+	queue := &jpsQueue{{at: start, f: j.heuristic(start)}}
+	for queue.Len() > 0 {
+		current := heap.Pop(queue).(*jpsNode)
+		if current.g > best[current.at] {
+			continue
+		}
+		j.expanded++
+		if j.goals[current.at] {
+			var points []tile
+			for n := current; n != nil; n = n.parent {
+				points = append([]tile{n.at}, points...)
+			}
+			return points, current.g
+		}
+
+		var parent *tile
+		if current.parent != nil {
+			parent = &current.parent.at
+		}
+		for _, d := range j.directions(current.at, parent) {
+			// A diagonal first step needs both sides open too
+			if d[0] != 0 && d[1] != 0 && (!j.open(current.at.x+d[0], current.at.y) || !j.open(current.at.x, current.at.y+d[1])) {
+				continue
+			}
+			point := j.jump(current.at.x, current.at.y, d[0], d[1])
+			if point == nil {
+				continue
+			}
+			g := current.g + j.distance(current.at, *point)
+			if old, ok := best[*point]; ok && old <= g {
+				continue
+			}
+			best[*point] = g
+			heap.Push(queue, &jpsNode{at: *point, g: g, f: g + j.heuristic(*point), parent: current})
+		}
+	}
+	return nil, -1
+}
+
+// expandJumpPoints fills in every tile between the jump points. Diagonal steps become two straight
+// moves because the cleaner can only move left, right, up and down, the corner is always open.
+func expandJumpPoints(points []tile) []tile {
+	if len(points) == 0 {
+		return nil
+	}
+	path := []tile{points[0]}
+	for i := 1; i < len(points); i++ {
+		current := points[i-1]
+		dx, dy := sign(points[i].x-current.x), sign(points[i].y-current.y)
+		for current != points[i] {
+			if dx != 0 && dy != 0 {
+				path = append(path, tile{current.x + dx, current.y})
+			}
+			current = tile{current.x + dx, current.y + dy}
+			path = append(path, current)
+		}
+	}
+	return path
+}
+
+// JPS has the same contract as AStar and returns a path of the same length, 4-connected.
+// The cleaner only moves 4-connected, so the 8-connected search is kept for the benchmark only:
+// its paths are shorter in diagonal steps but cost more moves once each diagonal is walked as two.
+func JPS(startX, startY int, room [][]string) []string {
+	// Jumping only works when every step costs the same, avoid zones need the weighted search
+	if activeOverlay.hasPenalties() {
+		return AStar(startX, startY, room)
+	}
+	goals := dirtiestTiles(room)
+	if len(goals) == 0 {
+		return nil
+	}
+	search := jumpPointSearch{room: room, goals: make(map[tile]bool)}
+	for _, goal := range goals {
+		search.goals[goal] = true
+	}
+	points, _ := search.search(tile{startX, startY})
+
+	var path []string
+	for _, t := range expandJumpPoints(points) {
+		path = append(path, t.String())
+	}
+	return path
+}
+
+// octileAStar is the 8-connected A* baseline for the benchmark, with the same corner rule as JPS
+func octileAStar(room [][]string, start, goal tile) (int, int) {
+	search := jumpPointSearch{room: room, goals: map[tile]bool{goal: true}, diagonal: true}
+	g := map[tile]int{start: 0}
+	closed := make(map[tile]bool)
+	queue := &flatQueue{{start, search.heuristic(start)}}
+	expanded := 0
+	for queue.Len() > 0 {
+		current := heap.Pop(queue).(flatNode).at
+		if closed[current] {
+			continue
+		}
+		closed[current] = true
+		expanded++
+		if current == goal {
+			return g[current], expanded
+		}
+		for _, d := range search.directions(current, nil) {
+			n := tile{current.x + d[0], current.y + d[1]}
+			if !search.open(n.x, n.y) {
+				continue
+			}
+			cost := straightCost
+			if d[0] != 0 && d[1] != 0 {
+				if !search.open(current.x+d[0], current.y) || !search.open(current.x, current.y+d[1]) {
+					continue
+				}
+				cost = diagonalCost
+			}
+			if old, ok := g[n]; !ok || g[current]+cost < old {
+				g[n] = g[current] + cost
+				heap.Push(queue, flatNode{n, g[n] + search.heuristic(n)})
+			}
+		}
+	}
+	return -1, expanded
+}
+
+// benchmarkJPS checks that jump point search finds paths as short as A* on a generated room
+// and compares how many nodes both expand, for 4- and 8-connected moves
+func benchmarkJPS(size int, queries int) {
+	room := generateRoom(size, size, 1)
+	rng := rand.New(rand.NewSource(3))
+	randomFloor := func() tile {
+		for {
+			t := tile{rng.Intn(size), rng.Intn(size)}
+			if !isWall(room, t.x, t.y) {
+				return t
+			}
+		}
+	}
+
+	fmt.Printf("Jump point search benchmark on a %dx%d room with %d queries\n", size, size, queries)
+	for _, diagonal := range []bool{false, true} {
+		var astarTime, jpsTime time.Duration
+		astarExpanded, jpsExpanded, mismatches := 0, 0, 0
+		for i := 0; i < queries; i++ {
+			start, goal := randomFloor(), randomFloor()
+
+			begin := time.Now()
+			var length, expanded int
+			if diagonal {
+				length, expanded = octileAStar(room, start, goal)
+			} else {
+				length, expanded = flatAStar(room, start, goal)
+				if length > 0 {
+					length *= straightCost
+				}
+			}
+			astarTime += time.Since(begin)
+			astarExpanded += expanded
+
+			search := jumpPointSearch{room: room, goals: map[tile]bool{goal: true}, diagonal: diagonal}
+			begin = time.Now()
+			_, cost := search.search(start)
+			jpsTime += time.Since(begin)
+			jpsExpanded += search.expanded
+
+			if cost != length {
+				mismatches++
+			}
+		}
+
+		name := "4-connected"
+		if diagonal {
+			name = "8-connected"
+		}
+		fmt.Printf("%s A*:  %v, %d nodes expanded\n", name, astarTime, astarExpanded)
+		fmt.Printf("%s JPS: %v, %d nodes expanded\n", name, jpsTime, jpsExpanded)
+		fmt.Printf("%s paths with a different length: %d\n", name, mismatches)
+	}
+}
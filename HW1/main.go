@@ -2,14 +2,79 @@ package main
 
 import (
 	"encoding/csv"
+	"flag"
 	"fmt"
 	"log"
 	"math"
+	"math/rand"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
 
+var roomPath string
+var analyze bool
+var savePath string
+var mode string
+var eventsPath string
+var obstaclesPath string
+var plannerName string
+var hpaBenchSize int
+var jpsBenchSize int
+var openDoorNames string
+var overlayPath string
+var jobsPath string
+var zonesList string
+var sensorNoise float64
+var sensorMiss float64
+var sensorRange int
+var sensorSeed int64
+var historyCommand string
+var historyPath string
+var batteryName string
+var pddlDir string
+var planPath string
+var searchName string
+var maxExpansions int
+var maxStates int
+
+// planners are the path finders the cleaning loop can use, they all share AStar's signature
+var planners = map[string]func(startX, startY int, room [][]string) []string{
+	"astar": AStar,
+	"hpa":   HPAStar,
+	"jps":   JPS,
+}
+
+func init() {
+	// parse the command line arguments for the room file and extra reports
+	flag.StringVar(&roomPath, "room", `C:\Users\37129\Intro_to_Ai\Intro_to_AI_hw\HW1\room.csv`, "Room file to clean (.csv, .json or .txt/.map ASCII map)")
+	flag.StringVar(&savePath, "save", "", "Save the loaded room to this file, the format follows the extension")
+	flag.StringVar(&mode, "mode", "astar", "Cleaning strategy: astar chases the dirtiest tiles, dstar does the same with incremental replanning, spacetime plans around moving obstacles, coverage sweeps the whole floor, house cleans a multi-room house, schedule runs the zone jobs from -jobs, belief cleans from noisy sensor readings, plan executes the PDDL plan from -plan, optimal searches the best plan for a small room and compares it with astar")
+	flag.StringVar(&zonesList, "zones", "", "Zones to add to the room, name,x1,y1,x2,y2 separated by semicolons, repeat a name to add more areas")
+	flag.StringVar(&jobsPath, "jobs", "", "File with zone cleaning jobs for the schedule mode, one zone,window,priority per line where the window is from-to, any or last")
+	flag.StringVar(&eventsPath, "events", "", "File with scripted wall changes, one tick,add|remove,x,y per line, not used by -mode coverage")
+	flag.StringVar(&obstaclesPath, "obstacles", "", "File with moving obstacles, name,path,x1,y1,... or name,random,x,y,seed per line")
+	flag.StringVar(&openDoorNames, "open-doors", "", "Comma separated closed doors to open for a house run")
+	flag.Float64Var(&sensorNoise, "sensor-noise", 20, "Standard deviation of the dirt sensor readings in the belief mode")
+	flag.Float64Var(&sensorMiss, "sensor-miss", 0.1, "Chance the dirt sensor reads a dirty tile as clean in the belief mode")
+	flag.IntVar(&sensorRange, "sensor-range", 1, "How many moves away the dirt sensor can read in the belief mode")
+	flag.Int64Var(&sensorSeed, "sensor-seed", 1, "Seed for the dirt sensor noise")
+	flag.StringVar(&historyCommand, "history", "off", "Dirt history: learn records this run and uses it as priors in the belief mode, show prints what was learned for the room, reset forgets it")
+	flag.StringVar(&historyPath, "history-file", "dirt_history.json", "File the dirt history is kept in")
+	flag.StringVar(&batteryName, "battery", "linear", "Battery model: linear drains the nominal energy, nonlinear drains more when low, charges fast to 80% and loses capacity with every cycle")
+	flag.StringVar(&pddlDir, "pddl", "", "Write domain.pddl and problem.pddl for the loaded room into this directory and exit")
+	flag.StringVar(&planPath, "plan", "", "PDDL plan to execute and validate in the plan mode")
+	flag.StringVar(&searchName, "search", "astar", "State-space search for the optimal mode: astar, ucs, idastar or best (greedy best-first, not optimal)")
+	flag.IntVar(&maxExpansions, "max-expansions", 5000000, "Stop the state-space search after expanding this many states")
+	flag.IntVar(&maxStates, "max-states", 2000000, "Stop the state-space search once it remembers this many states")
+	flag.StringVar(&overlayPath, "overlay", "", "File with virtual walls, no-go and avoid zones to apply on top of the room")
+	flag.StringVar(&plannerName, "planner", "astar", "Path finder for the astar cleaning loop: astar, hpa or jps, all 4-connected like the cleaner's moves")
+	flag.IntVar(&hpaBenchSize, "hpa-bench", 0, "Benchmark HPA* against flat A* on a generated room of this size and exit")
+	flag.IntVar(&jpsBenchSize, "jps-bench", 0, "Compare jump point search with A* on a generated room of this size, 4-connected like the cleaner and 8-connected for reference, and exit")
+	flag.BoolVar(&analyze, "analyze", false, "Print reachability and coverage analysis with the run report")
+}
+
 func (c *Cleaner) readCsvFile(filePath string) [][]string {
 	f, err := os.Open(filePath)
 	if err != nil {
@@ -32,7 +97,8 @@ func (c *Cleaner) readCsvFile(filePath string) [][]string {
 			fmt.Print("Error reading csv data", err)
 		}
 
-		value, err := strconv.Atoi(strings.TrimSpace(initialState[0]))
+		// Header values may be followed by a comment like "50 # Starting Battery"
+		value, err := strconv.Atoi(strings.TrimSpace(strings.Split(initialState[0], "#")[0]))
 		if err != nil {
 			fmt.Println("Error converting initial state value to int:", err)
 			continue
@@ -61,6 +127,17 @@ func (c *Cleaner) readCsvFile(filePath string) [][]string {
 	if err != nil {
 		log.Fatal("Error reading csv data", err)
 	}
+
+	// Zones may follow the header, written like in ASCII maps as zone=name,x1,y1,x2,y2
+	for len(records) > 0 && strings.HasPrefix(strings.TrimSpace(records[0][0]), "zone=") {
+		area, err := parseZone(strings.TrimPrefix(strings.TrimSpace(strings.Join(records[0], ",")), "zone="))
+		if err != nil {
+			fmt.Println("Error reading zone:", err)
+		} else {
+			currentRoom.zones = append(currentRoom.zones, area)
+		}
+		records = records[1:]
+	}
 	return records
 }
 
@@ -74,6 +151,15 @@ type Cleaner struct {
 	vacuumEnergy   int
 	dirtVolume     int
 	tilesCleaned   int
+	trail          []tile // every tile the cleaner has stood on, in order
+	ticks          int    // number of moves and cleans done so far, the simulation clock
+	collisions     int    // moves refused because a moving obstacle was in the way
+	waits          int    // ticks spent standing still to let a moving obstacle pass
+	capacity       int    // full charge, shrinks as the battery ages
+	designCapacity int    // full charge of a new battery
+	charged        int    // energy put back at docks, one capacity of it is a charge cycle
+	charges        int
+	socTimeline    []socSample
 }
 
 func (c *Cleaner) feedback(path []string) {
@@ -81,66 +167,107 @@ func (c *Cleaner) feedback(path []string) {
 	fmt.Println("Dirt volume:", c.dirtVolume)
 	fmt.Println("Path:", path)
 	fmt.Println("Tiles cleaned:", c.tilesCleaned)
+	if len(movingObstacles) > 0 {
+		fmt.Println("Collisions:", c.collisions)
+		fmt.Println("Waits:", c.waits)
+	}
 }
 func (c *Cleaner) moveLeft(room [][]string) {
-	if c.locationX > 0 && c.battery >= c.movementEnergy {
+	if c.locationX > 0 && c.battery >= c.cost(c.movementEnergy) {
 		nextTile := strings.TrimSpace(room[c.locationY][c.locationX-1])
 		if nextTile == "9001" {
 			fmt.Println("Cannot move left, there is a wall")
 			return
 		}
+		if isBlocked(room, c.locationX-1, c.locationY) {
+			fmt.Println("Cannot move left, there is a virtual wall or no-go zone")
+			return
+		}
+		if c.collides(room, c.locationX-1, c.locationY) {
+			fmt.Println("Cannot move left, collision with a moving obstacle")
+			return
+		}
 		c.locationX -= 1
-		c.battery -= c.movementEnergy
+		c.spend(c.movementEnergy)
+		c.ticks++
 	} else {
 		fmt.Println("You can't move left or not enough battery")
 	}
 }
 
 func (c *Cleaner) moveRight(room [][]string) {
-	if c.locationX < len(room[0])-1 && c.battery >= c.movementEnergy {
+	if c.locationX < len(room[0])-1 && c.battery >= c.cost(c.movementEnergy) {
 		nextTile := strings.TrimSpace(room[c.locationY][c.locationX+1])
 		if nextTile == "9001" {
 			fmt.Println("Cannot move right, there is a wall")
 			return
 		}
+		if isBlocked(room, c.locationX+1, c.locationY) {
+			fmt.Println("Cannot move right, there is a virtual wall or no-go zone")
+			return
+		}
+		if c.collides(room, c.locationX+1, c.locationY) {
+			fmt.Println("Cannot move right, collision with a moving obstacle")
+			return
+		}
 		c.locationX += 1
-		c.battery -= c.movementEnergy
+		c.spend(c.movementEnergy)
+		c.ticks++
 	} else {
 		fmt.Println("You can't move right or not enough battery")
 	}
 }
 
 func (c *Cleaner) moveUp(room [][]string) {
-	if c.locationY > 0 && c.battery >= c.movementEnergy {
+	if c.locationY > 0 && c.battery >= c.cost(c.movementEnergy) {
 		nextTile := strings.TrimSpace(room[c.locationY-1][c.locationX])
 		if nextTile == "9001" {
 			fmt.Println("Cannot move up, there is a wall")
 			return
 		}
+		if isBlocked(room, c.locationX, c.locationY-1) {
+			fmt.Println("Cannot move up, there is a virtual wall or no-go zone")
+			return
+		}
+		if c.collides(room, c.locationX, c.locationY-1) {
+			fmt.Println("Cannot move up, collision with a moving obstacle")
+			return
+		}
 		c.locationY -= 1
-		c.battery -= c.movementEnergy
+		c.spend(c.movementEnergy)
+		c.ticks++
 	} else {
 		fmt.Println("You can't move up or not enough battery")
 	}
 }
 
 func (c *Cleaner) moveDown(room [][]string) {
-	if c.locationY < len(room)-1 && c.battery >= c.movementEnergy {
+	if c.locationY < len(room)-1 && c.battery >= c.cost(c.movementEnergy) {
 		nextTile := strings.TrimSpace(room[c.locationY+1][c.locationX])
 		if nextTile == "9001" {
 			fmt.Println("Cannot move down, there is a wall")
 			return
 		}
+		if isBlocked(room, c.locationX, c.locationY+1) {
+			fmt.Println("Cannot move down, there is a virtual wall or no-go zone")
+			return
+		}
+		if c.collides(room, c.locationX, c.locationY+1) {
+			fmt.Println("Cannot move down, collision with a moving obstacle")
+			return
+		}
 		c.locationY += 1
-		c.battery -= c.movementEnergy
+		c.spend(c.movementEnergy)
+		c.ticks++
 	} else {
 		fmt.Println("You can't move down or not enough battery")
 	}
 }
 
 func (c *Cleaner) clean(room [][]string) {
-	if c.battery >= c.vacuumEnergy {
-		c.battery -= c.vacuumEnergy
+	if c.battery >= c.cost(c.vacuumEnergy) {
+		c.spend(c.vacuumEnergy)
+		c.ticks++
 		tileValue := room[c.locationY][c.locationX]
 		tileValue = strings.TrimSpace(tileValue)
 		tileValueInt, err := strconv.Atoi(tileValue)
@@ -150,6 +277,7 @@ func (c *Cleaner) clean(room [][]string) {
 		}
 		c.dirtVolume += tileValueInt
 		c.tilesCleaned += 1
+		roomEdits++
 		fmt.Println("Cleaning tile with value:", tileValue)
 	} else {
 		fmt.Println("You don't have enough battery")
@@ -171,8 +299,8 @@ func AStar(startX, startY int, room [][]string) []string {
 	for y, row := range room {
 		for x := range row {
 			valStr := strings.TrimSpace(room[y][x])
-			if valStr == "9001" {
-				continue // Skip walls
+			if isBlocked(room, x, y) {
+				continue // Skip walls and no-go zones
 			}
 			val, err := strconv.Atoi(valStr)
 			if err != nil || val <= 0 {
@@ -216,8 +344,7 @@ func AStar(startX, startY int, room [][]string) []string {
 		for _, d := range directions {
 			nx, ny := node.x+d[0], node.y+d[1]
 			if nx >= 0 && ny >= 0 && nx < len(room[0]) && ny < len(room) {
-				valStr := strings.TrimSpace(room[ny][nx])
-				if valStr != "9001" { // Skip walls
+				if !isBlocked(room, nx, ny) { // Skip walls and no-go zones
 					result = append(result, &Node{x: nx, y: ny})
 				}
 			}
@@ -268,7 +395,7 @@ func AStar(startX, startY int, room [][]string) []string {
 				continue
 			}
 
-			tentativeG := current.g + 1
+			tentativeG := current.g + stepCost(neighbor.x, neighbor.y) // Avoid zones cost extra
 			existing, exists := nodeMap[neighborKey]
 
 			if !exists || tentativeG < existing.g {
@@ -325,9 +452,18 @@ func (c *Cleaner) moveSomewhere(nextNode string, roomData [][]string) {
 	if c.locationY > y {
 		c.moveUp(roomData)
 	}
+	c.recordTrail()
 
 }
 
+// recordTrail remembers the current tile if the cleaner moved since the last record
+func (c *Cleaner) recordTrail() {
+	current := tile{c.locationX, c.locationY}
+	if len(c.trail) == 0 || c.trail[len(c.trail)-1] != current {
+		c.trail = append(c.trail, current)
+	}
+}
+
 
 // decideToClean is a simple function that decides whether the cleaner should clean the current tile or not, based on if the tiles value is above 0
 func (c *Cleaner) decideToClean(roomData [][]string) {
@@ -341,7 +477,95 @@ func (c *Cleaner) decideToClean(roomData [][]string) {
 
 }
 
+// maxStalls is how many plans in a row cleanWithAStar lets moving obstacles block before it gives up
+const maxStalls = 10
+
+// cleanWithAStar keeps walking to the dirtiest tiles until nothing is reachable or the battery is empty
+func (c *Cleaner) cleanWithAStar(roomData [][]string) []string {
+	totalPath := []string{}
+	c.recordTrail()
+	stalls := 0
+	for c.battery > 0 {
+		myPath := planners[plannerName](c.locationX, c.locationY, roomData) // Start at current location
+
+		if len(myPath) == 0 {
+			// A scripted event may still open a way, wait for it
+			if c.waitForNextEvent(roomData) {
+				continue
+			}
+			fmt.Println("No more paths to dirtiest tiles.")
+			break
+		}
+
+		// Go charge first when the trip would not leave enough to get back to a dock
+		walked, charged := c.rechargeIfNeeded(roomData, myPath)
+		totalPath = append(totalPath, walked...)
+		if charged || len(walked) > 0 {
+			// Plan again from wherever the cleaner ended up
+			continue
+		}
+
+		// Move the cleaner along the path
+		startX, startY, dirtBefore := c.locationX, c.locationY, c.dirtVolume
+		blocked := false
+		for i, node := range myPath {
+			if node == fmt.Sprintf("(%d,%d)", c.locationX, c.locationY) {
+				continue
+			}
+			c.moveSomewhere(node, roomData)
+			if node != fmt.Sprintf("(%d,%d)", c.locationX, c.locationY) {
+				// A moving obstacle or the battery stopped the step, keep what was walked and plan again
+				myPath = myPath[:i]
+				blocked = true
+				break
+			}
+			c.decideToClean(roomData)
+
+			// A wall moved, the rest of the path may be blocked so search again from scratch
+			if len(c.applyDueEvents(roomData)) > 0 {
+				myPath = myPath[:i+1]
+				break
+			}
+		}
+		// Add the path to the total path
+		totalPath = append(totalPath, myPath...)
+
+		if c.locationX != startX || c.locationY != startY || c.dirtVolume != dirtBefore {
+			stalls = 0
+			continue
+		}
+		// Nothing was walked or cleaned
+		if c.battery < c.cost(c.movementEnergy) {
+			fmt.Println("Not enough battery to reach the dirtiest tiles.")
+			break
+		}
+		if !blocked {
+			fmt.Println("No more paths to dirtiest tiles.")
+			break
+		}
+		stalls++
+		if stalls >= maxStalls {
+			fmt.Println("Moving obstacles keep blocking the way to the dirtiest tiles.")
+			break
+		}
+	}
+	return totalPath
+}
+
 func main() {
+	// parsed here and not in init, so go test can parse its own flags first
+	flag.Parse()
+	if hpaBenchSize > 0 {
+		benchmarkHPA(hpaBenchSize, 200)
+		return
+	}
+	if jpsBenchSize > 0 {
+		benchmarkJPS(jpsBenchSize, 200)
+		return
+	}
+	if planners[plannerName] == nil {
+		log.Fatal("Unknown planner " + plannerName)
+	}
 
 	// Create a new cleaner, which data will be overwritten by the csv file
 	cleaner := Cleaner{
@@ -355,29 +579,204 @@ func main() {
 		tilesCleaned:   0,
 	}
 
-	// Read the csv file and get all the data
-	roomData := cleaner.readCsvFile(`C:\Users\37129\Intro_to_Ai\Intro_to_AI_hw\HW1\room.csv`)
+	if overlayPath != "" {
+		activeOverlay = readOverlayFile(overlayPath)
+	}
+
+	model, ok := batteryModels[batteryName]
+	if !ok {
+		log.Fatal("Unknown battery model ", batteryName, ", use linear or nonlinear")
+	}
+	activeBattery = model
+
+	// A house is several room files joined by doors, it has its own loop and report
+	if mode == "house" {
+		if activeOverlay != nil {
+			fmt.Println("Ignoring the overlay, its coordinates can't tell the rooms of a house apart")
+			activeOverlay = nil
+		}
+		openDoors = make(map[string]bool)
+		for _, name := range strings.Split(openDoorNames, ",") {
+			openDoors[strings.TrimSpace(name)] = true
+		}
+		home, current := cleaner.loadHouse(roomPath)
+		cleaner.initBattery()
+		totalPath := cleaner.cleanHouse(home, current)
+		cleaner.feedback(totalPath)
+		home.print()
+		return
+	}
+
+	// Read the room file and get all the data
+	roomData := cleaner.loadRoom(roomPath)
+	if zonesList != "" {
+		for _, value := range strings.Split(zonesList, ";") {
+			area, err := parseZone(value)
+			if err != nil {
+				log.Fatal("Error reading -zones: ", err)
+			}
+			currentRoom.zones = append(currentRoom.zones, area)
+		}
+	}
+	cleaner.initBattery()
+	if activeOverlay != nil && activeOverlay.blocked[tile{cleaner.locationX, cleaner.locationY}] {
+		fmt.Println("The cleaner starts inside a virtual wall or no-go zone, it won't be able to move")
+	}
 	totalPath := []string{}
 	fmt.Println(roomData)
-	for cleaner.battery > 0 {
-		myPath := AStar(cleaner.locationX, cleaner.locationY, roomData) // Start at current location
+	if savePath != "" {
+		if err := cleaner.saveRoom(savePath, roomData); err != nil {
+			fmt.Println("Error saving room:", err)
+		} else {
+			fmt.Println("Room saved to", savePath)
+		}
+	}
 
-		if len(myPath) == 0 {
-			fmt.Println("No more paths to dirtiest tiles.")
-			break
+	if pddlDir != "" {
+		name := strings.TrimSuffix(filepath.Base(roomPath), filepath.Ext(roomPath))
+		name = strings.Map(func(r rune) rune {
+			if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' {
+				return r
+			}
+			return '-'
+		}, name)
+		if err := cleaner.exportPddl(pddlDir, roomData, name); err != nil {
+			log.Fatal("Unable to write PDDL files ", err)
 		}
+		fmt.Println("PDDL domain and problem written to", pddlDir)
+		return
+	}
 
-		// Move the cleaner along the path
-		for _, node := range myPath {
-			if node == fmt.Sprintf("(%d,%d)", cleaner.locationX, cleaner.locationY) {
-				continue
+	// The history is keyed by the walls, so look it up before anything else touches the room
+	var history *dirtHistory
+	var learned *roomHistory
+	var found map[tile]int
+	roomID := roomIdentity(roomData)
+	if historyCommand != "off" {
+		var err error
+		history, err = readHistory(historyPath)
+		if err != nil {
+			log.Fatal("Unable to read dirt history ", err)
+		}
+		learned = history.Rooms[roomID]
+		switch historyCommand {
+		case "show":
+			learned.print(roomID, roomData)
+			return
+		case "reset":
+			delete(history.Rooms, roomID)
+			if err := history.write(historyPath); err != nil {
+				log.Fatal("Unable to write dirt history ", err)
 			}
-			cleaner.moveSomewhere(node, roomData)
-			cleaner.decideToClean(roomData)
+			fmt.Println("Forgot the dirt history of room", roomID)
+			return
+		case "learn":
+			found = initialDirt(roomData)
+		default:
+			log.Fatal("Unknown history command ", historyCommand, ", use off, learn, show or reset")
 		}
-		// Add the path to the total path
-		totalPath = append(totalPath, myPath...)
+	}
+
+	// Analyse the room before cleaning changes it
+	var analysis roomAnalysis
+	if analyze {
+		analysis = analyzeRoom(&cleaner, roomData)
+	}
+	if eventsPath != "" {
+		if mode == "coverage" {
+			// The A* run of the comparison would use the events up and the sweep never looks at them
+			log.Fatal("The coverage mode plans its sweep up front and does not follow -events, run it without them")
+		}
+		obstacleEvents = readEventsFile(eventsPath)
+	}
+	if obstaclesPath != "" {
+		movingObstacles = readObstaclesFile(obstaclesPath)
+	}
+
+	var comparison []cleaningStats
+	var replans *replanStats
+	var jobs []*cleaningJob
+	var belief *beliefMap
+	var beliefResult beliefStats
+	switch mode {
+	case "optimal":
+		switch searchName {
+		case "astar", "ucs", "idastar", "best":
+		default:
+			log.Fatal("Unknown search ", searchName, ", use astar, ucs, idastar or best")
+		}
+		totalPath = cleaner.compareWithOptimal(roomData, searchName, maxExpansions, maxStates)
+	case "plan":
+		if planPath == "" {
+			log.Fatal("The plan mode needs a plan file, pass it with -plan")
+		}
+		plan, err := readPlanFile(planPath)
+		if err != nil {
+			log.Fatal("Unable to read plan ", err)
+		}
+		totalPath, err = cleaner.executePlan(roomData, plan)
+		if err != nil {
+			fmt.Println("Plan rejected:", err)
+		} else {
+			fmt.Println("Plan valid:", len(plan), "actions executed")
+		}
+	case "belief":
+		sensor := &dirtSensor{noise: sensorNoise, missRate: sensorMiss, radius: sensorRange, rng: rand.New(rand.NewSource(sensorSeed))}
+		belief = newBeliefMap(roomData, sensor, learned)
+		totalPath, beliefResult = cleaner.cleanWithBelief(roomData, belief)
+		// The cleaner only learns what its sensor showed it
+		found = belief.observed()
+	case "schedule":
+		if jobsPath == "" {
+			log.Fatal("The schedule mode needs a jobs file, pass it with -jobs")
+		}
+		jobs = readJobsFile(jobsPath)
+		if len(jobs) > 0 && len(currentRoom.zones) == 0 {
+			log.Fatal("The jobs in " + jobsPath + " name zones but " + roomPath + " has none, add zone= lines to the room file or pass -zones")
+		}
+		totalPath = cleaner.cleanWithSchedule(roomData, jobs)
+	case "spacetime":
+		totalPath = cleaner.cleanWithSpaceTime(roomData)
+	case "dstar":
+		var stats replanStats
+		totalPath, stats = cleaner.cleanWithDStar(roomData)
+		replans = &stats
+	case "coverage":
+		// Run the dirt chasing loop on copies so both strategies start from the same room
+		astarCleaner := cleaner
+		astarStats, _ := astarCleaner.measureRun("astar", copyRoom(roomData), (*Cleaner).cleanWithAStar)
+		var coverageStats cleaningStats
+		coverageStats, totalPath = cleaner.measureRun("coverage", roomData, (*Cleaner).cleanWithCoverage)
+		comparison = []cleaningStats{coverageStats, astarStats}
+	default:
+		totalPath = cleaner.cleanWithAStar(roomData)
 	}
 	cleaner.feedback(totalPath)
+	if batteryName != "linear" || cleaner.charges > 0 {
+		cleaner.printCharge()
+	}
+	if len(comparison) > 0 {
+		printComparison(comparison)
+	}
+	if replans != nil {
+		replans.print()
+	}
+	if jobs != nil {
+		printSchedule(jobs)
+	}
+	if belief != nil {
+		belief.print(roomData, beliefResult)
+	}
+	if historyCommand == "learn" {
+		history.record(roomID, roomData, found)
+		if err := history.write(historyPath); err != nil {
+			fmt.Println("Error saving dirt history:", err)
+		} else {
+			fmt.Println("Dirt history of room", roomID, "saved to", historyPath)
+		}
+	}
+	if analyze {
+		analysis.print()
+	}
 
 }
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"container/heap"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// movingObstacle is a pet or a person walking through the room. Scripted obstacles walk their
+// path back and forth one tile per tick, random walkers pick a random neighbour or stay put.
+type movingObstacle struct {
+	name       string
+	path       []tile // scripted positions, or the visited positions so far for a random walker
+	randomWalk bool
+	rng        *rand.Rand
+}
+
+// movingObstacles are the obstacles for the current run
+var movingObstacles []*movingObstacle
+
+// readObstaclesFile loads obstacles written one per line as either
+// name,path,x1,y1,x2,y2,... for a scripted walk or name,random,x,y,seed for a random walk
+func readObstaclesFile(filePath string) []*movingObstacle {
+	f, err := os.Open(filePath)
+	if err != nil {
+		log.Fatal("Unable to read obstacles file "+filePath, err)
+	}
+	defer func(f *os.File) {
+		err := f.Close()
+		if err != nil {
+			fmt.Println("Error closing file:", err)
+		}
+	}(f)
+
+	csvReader := csv.NewReader(f)
+	csvReader.FieldsPerRecord = -1
+	csvReader.Comment = '#'
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		log.Fatal("Error reading obstacles data", err)
+	}
+
+	var obstacles []*movingObstacle
+	for _, record := range records {
+		if len(record) < 4 {
+			fmt.Println("Skipping obstacle, not enough values:", record)
+			continue
+		}
+		values := make([]int, 0, len(record)-2)
+		for _, field := range record[2:] {
+			value, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil {
+				fmt.Println("Error converting obstacle value to int:", err)
+				break
+			}
+			values = append(values, value)
+		}
+		if len(values) != len(record)-2 {
+			continue
+		}
+
+		obstacle := &movingObstacle{name: strings.TrimSpace(record[0])}
+		switch strings.TrimSpace(record[1]) {
+		case "path":
+			if len(values)%2 != 0 {
+				fmt.Println("Skipping obstacle", obstacle.name, "path needs x,y pairs")
+				continue
+			}
+			for i := 0; i < len(values); i += 2 {
+				obstacle.path = append(obstacle.path, tile{values[i], values[i+1]})
+			}
+		case "random":
+			if len(values) != 3 {
+				fmt.Println("Skipping obstacle", obstacle.name, "random walk needs x,y,seed")
+				continue
+			}
+			obstacle.randomWalk = true
+			obstacle.path = []tile{{values[0], values[1]}}
+			obstacle.rng = rand.New(rand.NewSource(int64(values[2])))
+		default:
+			fmt.Println("Skipping obstacle with unknown movement:", record[1])
+			continue
+		}
+		obstacles = append(obstacles, obstacle)
+	}
+	return obstacles
+}
+
+// positionAt returns where the obstacle is at the given tick
+func (o *movingObstacle) positionAt(room [][]string, tick int) tile {
+	if tick < 0 {
+		tick = 0
+	}
+	if o.randomWalk {
+		// Extend the walk until it reaches the tick, the seed makes every run the same
+		for len(o.path) <= tick {
+			current := o.path[len(o.path)-1]
+			options := append([]tile{current}, openNeighbors(room, current)...)
+			o.path = append(o.path, options[o.rng.Intn(len(options))])
+		}
+		return o.path[tick]
+	}
+
+	// Walk the scripted path forwards and then back again
+	if len(o.path) == 1 {
+		return o.path[0]
+	}
+	period := 2 * (len(o.path) - 1)
+	step := tick % period
+	if step >= len(o.path) {
+		step = period - step
+	}
+	return o.path[step]
+}
+
+// predictedAt is where the planner expects the obstacle to be at tick, knowing the clock is now.
+// Scripted obstacles are known exactly, a random walker is assumed to stay where it is.
+func (o *movingObstacle) predictedAt(room [][]string, tick, now int) tile {
+	if o.randomWalk {
+		return o.positionAt(room, now)
+	}
+	return o.positionAt(room, tick)
+}
+
+// collides checks the target tile against the moving obstacles. Bumping into one costs a tick
+// but no energy, the obstacle gets the chance to walk away.
+func (c *Cleaner) collides(room [][]string, x, y int) bool {
+	for _, o := range movingObstacles {
+		if o.positionAt(room, c.ticks) == (tile{x, y}) {
+			fmt.Println("Bumped into", o.name, "at", tile{x, y})
+			c.collisions++
+			c.ticks++
+			return true
+		}
+	}
+	return false
+}
+
+// wait keeps the cleaner in place for one tick
+func (c *Cleaner) wait() {
+	c.waits++
+	c.ticks++
+}
+
+// reserved reports whether any obstacle is expected on the tile at tick
+func reserved(room [][]string, at tile, tick, now int) bool {
+	for _, o := range movingObstacles {
+		if o.predictedAt(room, tick, now) == at {
+			return true
+		}
+	}
+	return false
+}
+
+type spaceTimeNode struct {
+	at     tile
+	tick   int
+	g, f   int
+	parent *spaceTimeNode
+}
+
+type spaceTimeQueue []*spaceTimeNode
+
+func (q spaceTimeQueue) Len() int { return len(q) }
+func (q spaceTimeQueue) Less(i, j int) bool {
+	return q[i].f < q[j].f || (q[i].f == q[j].f && q[i].tick > q[j].tick)
+}
+func (q spaceTimeQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *spaceTimeQueue) Push(x interface{}) { *q = append(*q, x.(*spaceTimeNode)) }
+func (q *spaceTimeQueue) Pop() interface{} {
+	old := *q
+	node := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return node
+}
+
+// spaceTimeAStar searches over (tile, tick) so the cleaner can move or wait to stay clear of the
+// predicted obstacle positions. It returns the tiles for every tick after now, waits repeat a tile.
+func spaceTimeAStar(room [][]string, start tile, now int, goals []tile, horizon int) []tile {
+	heuristic := func(t tile) int {
+		best := -1
+		for _, goal := range goals {
+			if d := abs(t.x-goal.x) + abs(t.y-goal.y); best == -1 || d < best {
+				best = d
+			}
+		}
+		return best
+	}
+	isGoal := make(map[tile]bool)
+	for _, goal := range goals {
+		isGoal[goal] = true
+	}
+
+	type state struct {
+		at   tile
+		tick int
+	}
+	closed := make(map[state]bool)
+	open := &spaceTimeQueue{{at: start, tick: now, f: heuristic(start)}}
+	for open.Len() > 0 {
+		current := heap.Pop(open).(*spaceTimeNode)
+		if isGoal[current.at] {
+			var path []tile
+			for ; current.parent != nil; current = current.parent {
+				path = append([]tile{current.at}, path...)
+			}
+			return path
+		}
+		if closed[state{current.at, current.tick}] || current.tick-now >= horizon {
+			continue
+		}
+		closed[state{current.at, current.tick}] = true
+
+		// Moving into a tile needs it free now and on arrival, waiting needs our own tile free on the next tick
+		options := append([]tile{current.at}, openNeighbors(room, current.at)...)
+		for i, next := range options {
+			if i > 0 && reserved(room, next, current.tick, now) {
+				continue
+			}
+			if reserved(room, next, current.tick+1, now) || closed[state{next, current.tick + 1}] {
+				continue
+			}
+			// Waiting costs a tick, moving costs whatever the overlay charges for the tile
+			g := current.g + 1
+			if i > 0 {
+				g = current.g + stepCost(next.x, next.y)
+			}
+			heap.Push(open, &spaceTimeNode{at: next, tick: current.tick + 1, g: g, f: g + heuristic(next), parent: current})
+		}
+	}
+	return nil
+}
+
+// cleanWithSpaceTime chases the dirtiest tiles while planning around moving obstacles.
+// It replans after every action because vacuuming also moves the clock forward.
+func (c *Cleaner) cleanWithSpaceTime(roomData [][]string) []string {
+	c.recordTrail()
+	totalPath := []string{tile{c.locationX, c.locationY}.String()}
+
+	// Long enough to walk the whole room twice over while waiting for obstacles
+	horizon := 0
+	for _, row := range roomData {
+		horizon += 2 * len(row)
+	}
+
+	stuck := 0
+	for c.battery >= c.cost(c.movementEnergy) {
+		goals := dirtiestTiles(roomData)
+		if len(goals) == 0 {
+			fmt.Println("No more paths to dirtiest tiles.")
+			break
+		}
+		current := tile{c.locationX, c.locationY}
+		path := spaceTimeAStar(roomData, current, c.ticks, goals, horizon)
+		if len(path) == 0 {
+			// A random walker may be blocking a doorway, give it time to leave if the walls allow a path
+			if stuck < horizon && reachableAny(roomData, current, goals) {
+				stuck++
+				c.wait()
+				continue
+			}
+			fmt.Println("No more paths to dirtiest tiles.")
+			break
+		}
+		stuck = 0
+
+		next := path[0]
+		if next == current {
+			c.wait()
+			continue
+		}
+		c.moveSomewhere(next.String(), roomData)
+		if c.locationX == next.x && c.locationY == next.y {
+			totalPath = append(totalPath, next.String())
+			c.decideToClean(roomData)
+		}
+	}
+	return totalPath
+}
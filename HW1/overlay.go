@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// defaultAvoidPenalty is the extra cost of stepping into a soft avoid zone when the file gives none
+const defaultAvoidPenalty = 5
+
+// overlay holds the restrictions a user draws on top of a room without changing the room file.
+// Virtual walls and no-go zones can't be entered at all, avoid zones only cost planners extra.
+type overlay struct {
+	blocked map[tile]bool
+	penalty map[tile]int
+}
+
+// activeOverlay is the overlay for the current run, nil when there is none
+var activeOverlay *overlay
+
+// readOverlayFile loads one restriction per line:
+//
+//	wall,x1,y1,x2,y2          virtual wall along a row or a column
+//	nogo,x1,y1,x2,y2          rectangle the cleaner must not enter
+//	avoid,x1,y1,x2,y2,penalty rectangle planners try to stay out of
+func readOverlayFile(filePath string) *overlay {
+	f, err := os.Open(filePath)
+	if err != nil {
+		log.Fatal("Unable to read overlay file "+filePath, err)
+	}
+	defer func(f *os.File) {
+		err := f.Close()
+		if err != nil {
+			fmt.Println("Error closing file:", err)
+		}
+	}(f)
+
+	csvReader := csv.NewReader(f)
+	csvReader.FieldsPerRecord = -1
+	csvReader.Comment = '#'
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		log.Fatal("Error reading overlay data", err)
+	}
+
+	o := &overlay{blocked: make(map[tile]bool), penalty: make(map[tile]int)}
+	for _, record := range records {
+		if len(record) < 5 {
+			fmt.Println("Skipping overlay line, expected kind,x1,y1,x2,y2:", record)
+			continue
+		}
+		values := make([]int, 0, len(record)-1)
+		for _, field := range record[1:] {
+			value, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil {
+				fmt.Println("Error converting overlay value to int:", err)
+				break
+			}
+			values = append(values, value)
+		}
+		if len(values) != len(record)-1 {
+			continue
+		}
+
+		x1, y1, x2, y2 := min(values[0], values[2]), min(values[1], values[3]), max(values[0], values[2]), max(values[1], values[3])
+		kind := strings.TrimSpace(record[0])
+		switch kind {
+		case "wall":
+			if x1 != x2 && y1 != y2 {
+				fmt.Println("Skipping virtual wall, it has to follow a row or a column:", record)
+				continue
+			}
+			fallthrough
+		case "nogo":
+			for y := y1; y <= y2; y++ {
+				for x := x1; x <= x2; x++ {
+					o.blocked[tile{x, y}] = true
+				}
+			}
+		case "avoid":
+			penalty := defaultAvoidPenalty
+			if len(values) > 4 {
+				penalty = values[4]
+			}
+			for y := y1; y <= y2; y++ {
+				for x := x1; x <= x2; x++ {
+					o.penalty[tile{x, y}] = max(o.penalty[tile{x, y}], penalty)
+				}
+			}
+		default:
+			fmt.Println("Skipping overlay line with unknown kind:", kind)
+		}
+	}
+	return o
+}
+
+// hasPenalties reports whether some steps cost more than others
+func (o *overlay) hasPenalties() bool {
+	return o != nil && len(o.penalty) > 0
+}
+
+// isBlocked reports whether the cleaner may not enter the tile, either because of a real wall
+// or because the overlay forbids it
+func isBlocked(room [][]string, x, y int) bool {
+	if isWall(room, x, y) {
+		return true
+	}
+	return activeOverlay != nil && activeOverlay.blocked[tile{x, y}]
+}
+
+// stepCost is what planners pay for stepping onto a tile, one plus any avoid zone penalty
+func stepCost(x, y int) int {
+	if activeOverlay == nil {
+		return 1
+	}
+	return 1 + activeOverlay.penalty[tile{x, y}]
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// pddlDomain is the cleaning domain. Tiles are objects, walls are simply missing adjacency facts
+// and the battery is a numeric fluent both actions drain, like the Cleaner does with a linear battery.
+const pddlDomain = `(define (domain vacuum-cleaner)
+  (:requirements :strips :typing :numeric-fluents)
+  (:types tile)
+  (:predicates
+    (at ?t - tile)
+    (adjacent ?from ?to - tile)
+    (dirty ?t - tile)
+    (cleaned ?t - tile))
+  (:functions
+    (battery)
+    (move-energy)
+    (vacuum-energy)
+    (dirt ?t - tile)
+    (collected)
+    (total-cost))
+
+  (:action move
+    :parameters (?from ?to - tile)
+    :precondition (and (at ?from) (adjacent ?from ?to) (>= (battery) (move-energy)))
+    :effect (and (not (at ?from)) (at ?to)
+                 (decrease (battery) (move-energy))
+                 (increase (total-cost) (move-energy))))
+
+  (:action vacuum
+    :parameters (?t - tile)
+    :precondition (and (at ?t) (dirty ?t) (>= (battery) (vacuum-energy)))
+    :effect (and (not (dirty ?t)) (cleaned ?t)
+                 (decrease (battery) (vacuum-energy))
+                 (increase (collected) (dirt ?t))
+                 (increase (total-cost) (vacuum-energy))))
+)
+`
+
+func pddlTile(t tile) string {
+	return fmt.Sprintf("t_%d_%d", t.x, t.y)
+}
+
+// parsePddlTile only accepts names exactly as pddlTile writes them, so t_1_2x or t_01_2 are rejected
+func parsePddlTile(name string) (tile, error) {
+	var t tile
+	coords, ok := strings.CutPrefix(name, "t_")
+	xs, ys, found := strings.Cut(coords, "_")
+	x, errX := strconv.Atoi(xs)
+	y, errY := strconv.Atoi(ys)
+	if !ok || !found || errX != nil || errY != nil || pddlTile(tile{x, y}) != name {
+		return t, fmt.Errorf("%q is not a tile name", name)
+	}
+	return tile{x, y}, nil
+}
+
+// pddlGoals are the dirty tiles reachable from start, every one of them has to end up cleaned
+func pddlGoals(room [][]string, start tile) []tile {
+	dist := distanceMap(room, start)
+	var goals []tile
+	for y := range room {
+		for x := range room[y] {
+			if !isBlocked(room, x, y) && tileDirt(room, x, y) > 0 && dist[y][x] >= 0 {
+				goals = append(goals, tile{x, y})
+			}
+		}
+	}
+	return goals
+}
+
+// pddlProblem describes the room as it is now. The goal is every dirty tile the cleaner can reach,
+// with a small battery that may have no solution, which a planner will report. A cleaner standing
+// on a blocked tile has no tile object to be at, so there is no problem to write.
+func (c *Cleaner) pddlProblem(room [][]string, name string) (string, error) {
+	start := tile{c.locationX, c.locationY}
+	if isBlocked(room, start.x, start.y) {
+		return "", fmt.Errorf("the cleaner starts on %v, which is a wall or blocked by the overlay", start)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "(define (problem %s)\n  (:domain vacuum-cleaner)\n  (:objects", name)
+	var tiles []tile
+	for y := range room {
+		for x := range room[y] {
+			if !isBlocked(room, x, y) {
+				tiles = append(tiles, tile{x, y})
+			}
+		}
+	}
+	for i, t := range tiles {
+		if i%10 == 0 {
+			sb.WriteString("\n   ")
+		}
+		sb.WriteString(" " + pddlTile(t))
+	}
+	sb.WriteString(" - tile)\n  (:init\n")
+
+	fmt.Fprintf(&sb, "    (at %s)\n", pddlTile(start))
+	fmt.Fprintf(&sb, "    (= (battery) %d)\n    (= (move-energy) %d)\n    (= (vacuum-energy) %d)\n", c.battery, c.movementEnergy, c.vacuumEnergy)
+	sb.WriteString("    (= (collected) 0)\n    (= (total-cost) 0)\n")
+	for _, t := range tiles {
+		for _, n := range openNeighbors(room, t) {
+			fmt.Fprintf(&sb, "    (adjacent %s %s)\n", pddlTile(t), pddlTile(n))
+		}
+	}
+
+	for _, t := range tiles {
+		if dirt := tileDirt(room, t.x, t.y); dirt > 0 {
+			fmt.Fprintf(&sb, "    (dirty %s)\n    (= (dirt %s) %d)\n", pddlTile(t), pddlTile(t), dirt)
+		}
+	}
+	sb.WriteString("  )\n  (:goal (and")
+	for _, t := range pddlGoals(room, start) {
+		fmt.Fprintf(&sb, "\n    (cleaned %s)", pddlTile(t))
+	}
+	sb.WriteString("))\n  (:metric minimize (total-cost))\n)\n")
+	return sb.String(), nil
+}
+
+// exportPddl writes domain.pddl and problem.pddl into the directory
+func (c *Cleaner) exportPddl(dir string, room [][]string, name string) error {
+	problem, err := c.pddlProblem(room, name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "domain.pddl"), []byte(pddlDomain), 0644); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "problem.pddl"), []byte(problem), 0644)
+}
+
+// pddlAction is one step of a plan, the action name and its arguments in lower case
+type pddlAction struct {
+	line int
+	name string
+	args []string
+}
+
+// readPlanFile reads the usual planner output, one action per line like
+// "0: (move t_0_0 t_1_0) [1]". Anything outside the parentheses and everything after ; is ignored.
+func readPlanFile(filePath string) ([]pddlAction, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer func(f *os.File) {
+		err := f.Close()
+		if err != nil {
+			fmt.Println("Error closing file:", err)
+		}
+	}(f)
+
+	var plan []pddlAction
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), ";")
+		open, end := strings.Index(text, "("), strings.LastIndex(text, ")")
+		if open == -1 && strings.TrimSpace(text) == "" {
+			continue
+		}
+		if open == -1 || end < open {
+			return nil, fmt.Errorf("line %d: expected an action in parentheses, got %q", line, strings.TrimSpace(text))
+		}
+		fields := strings.Fields(strings.ToLower(text[open+1 : end]))
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("line %d: empty action", line)
+		}
+		plan = append(plan, pddlAction{line: line, name: fields[0], args: fields[1:]})
+	}
+	return plan, scanner.Err()
+}
+
+// executePlan runs the plan in the simulator and stops at the first action the Cleaner rules
+// don't allow, returning the path walked so far and the reason. A plan that runs to the end
+// but leaves a goal tile of the problem dirty is rejected too.
+func (c *Cleaner) executePlan(room [][]string, plan []pddlAction) ([]string, error) {
+	goals := pddlGoals(room, tile{c.locationX, c.locationY})
+	totalPath := []string{tile{c.locationX, c.locationY}.String()}
+	c.recordTrail()
+	for i, action := range plan {
+		fail := func(format string, a ...interface{}) ([]string, error) {
+			return totalPath, fmt.Errorf("step %d (line %d) %s: %s", i+1, action.line, action.name, fmt.Sprintf(format, a...))
+		}
+		here := tile{c.locationX, c.locationY}
+		switch action.name {
+		case "move":
+			if len(action.args) != 2 {
+				return fail("expected 2 arguments, got %d", len(action.args))
+			}
+			from, err := parsePddlTile(action.args[0])
+			if err != nil {
+				return fail("%v", err)
+			}
+			to, err := parsePddlTile(action.args[1])
+			if err != nil {
+				return fail("%v", err)
+			}
+			if from != here {
+				return fail("the cleaner is at %v, not %v", here, from)
+			}
+			if abs(from.x-to.x)+abs(from.y-to.y) != 1 {
+				return fail("%v and %v are not adjacent", from, to)
+			}
+			if !inBounds(room, to.x, to.y) || isBlocked(room, to.x, to.y) {
+				return fail("%v is a wall", to)
+			}
+			if c.battery < c.cost(c.movementEnergy) {
+				return fail("battery %d is too low to move", c.battery)
+			}
+			c.moveSomewhere(to.String(), room)
+			if c.locationX != to.x || c.locationY != to.y {
+				return fail("the cleaner could not move to %v", to)
+			}
+			totalPath = append(totalPath, to.String())
+		case "vacuum":
+			if len(action.args) != 1 {
+				return fail("expected 1 argument, got %d", len(action.args))
+			}
+			at, err := parsePddlTile(action.args[0])
+			if err != nil {
+				return fail("%v", err)
+			}
+			if at != here {
+				return fail("the cleaner is at %v, not %v", here, at)
+			}
+			if tileDirt(room, at.x, at.y) <= 0 {
+				return fail("%v is not dirty", at)
+			}
+			if c.battery < c.cost(c.vacuumEnergy) {
+				return fail("battery %d is too low to vacuum", c.battery)
+			}
+			c.decideToClean(room)
+		default:
+			return fail("unknown action")
+		}
+	}
+
+	var dirty []tile
+	for _, t := range goals {
+		if tileDirt(room, t.x, t.y) > 0 {
+			dirty = append(dirty, t)
+		}
+	}
+	if len(dirty) > 0 {
+		return totalPath, fmt.Errorf("the plan ends with %d of %d goal tiles still dirty: %v", len(dirty), len(goals), dirty)
+	}
+	return totalPath, nil
+}
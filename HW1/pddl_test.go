@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+// testCleaner loads room.csv the way main does, with the linear battery and no overlay
+func testCleaner(t *testing.T) (*Cleaner, [][]string) {
+	t.Helper()
+	activeBattery, activeOverlay = batteryModels["linear"], nil
+	c := &Cleaner{name: "Rummba", battery: 50, movementEnergy: 1, vacuumEnergy: 5}
+	room := c.loadRoom("room.csv")
+	if room == nil {
+		t.Fatal("room.csv did not load")
+	}
+	c.initBattery()
+	return c, room
+}
+
+func TestParsePddlTile(t *testing.T) {
+	for _, want := range []tile{{0, 0}, {3, 7}, {12, 105}} {
+		got, err := parsePddlTile(pddlTile(want))
+		if err != nil || got != want {
+			t.Errorf("%s comes back as %v, %v", pddlTile(want), got, err)
+		}
+	}
+	for _, name := range []string{"t_1_2x", "t_01_2", "t_1", "t_-0_2", "x_1_2", "t_1_2_3", ""} {
+		if got, err := parsePddlTile(name); err == nil {
+			t.Errorf("%q is read as %v, want an error", name, got)
+		}
+	}
+}
+
+// TestPddlProblemTiles checks every tile the problem names can be read back and is open
+func TestPddlProblemTiles(t *testing.T) {
+	c, room := testCleaner(t)
+	problem, err := c.pddlProblem(room, "room")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := regexp.MustCompile(`t_[0-9_]+`).FindAllString(problem, -1)
+	if len(names) == 0 {
+		t.Fatal("the problem names no tiles")
+	}
+	for _, name := range names {
+		p, err := parsePddlTile(name)
+		if err != nil {
+			t.Errorf("the problem names %s: %v", name, err)
+		} else if isBlocked(room, p.x, p.y) {
+			t.Errorf("the problem names %s, which is a wall", name)
+		}
+	}
+}
+
+func TestPddlProblemBlockedStart(t *testing.T) {
+	c, room := testCleaner(t)
+	activeOverlay = &overlay{blocked: map[tile]bool{{c.locationX, c.locationY}: true}, penalty: map[tile]int{}}
+	defer func() { activeOverlay = nil }()
+	if _, err := c.pddlProblem(room, "room"); err == nil {
+		t.Error("a cleaner on a blocked tile gets a problem")
+	}
+}
+
+func TestReadPlanFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.txt")
+	plan := "; found by some planner\n0: (move t_0_0 t_1_0) [1]\n\n1: (VACUUM T_1_0) [5] ; the dirt\n(move t_1_0 t_1_1)\n"
+	if err := os.WriteFile(path, []byte(plan), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readPlanFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []pddlAction{
+		{line: 2, name: "move", args: []string{"t_0_0", "t_1_0"}},
+		{line: 4, name: "vacuum", args: []string{"t_1_0"}},
+		{line: 5, name: "move", args: []string{"t_1_0", "t_1_1"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if err := os.WriteFile(path, []byte("0: move t_0_0 t_1_0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readPlanFile(path); err == nil {
+		t.Error("an action without parentheses is read")
+	}
+}
@@ -0,0 +1,434 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// roomDetails holds the parts of a room file that don't fit in the plain grid.
+// Only the JSON format can describe terrain, ASCII maps and JSON can both describe docks,
+// every format can describe zones.
+type roomDetails struct {
+	docks   []tile
+	terrain [][]string // floor type per tile like "carpet" or "tile", nil if the file has none
+	zones   []zoneArea
+}
+
+// zoneArea is a named rectangle of the room, areas sharing a name make up one zone like "kitchen"
+type zoneArea struct {
+	name     string
+	from, to tile // opposite corners, both inclusive
+}
+
+func (a zoneArea) contains(t tile) bool {
+	return min(a.from.x, a.to.x) <= t.x && t.x <= max(a.from.x, a.to.x) &&
+		min(a.from.y, a.to.y) <= t.y && t.y <= max(a.from.y, a.to.y)
+}
+
+// inZone reports whether the tile belongs to any area of the named zone
+func inZone(name string, t tile) bool {
+	for _, area := range currentRoom.zones {
+		if area.name == name && area.contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseZone reads name,x1,y1,x2,y2 as written after zone= in ASCII maps and CSV headers
+func parseZone(value string) (zoneArea, error) {
+	fields := strings.Split(value, ",")
+	if len(fields) != 5 {
+		return zoneArea{}, fmt.Errorf("expected name,x1,y1,x2,y2, got %q", value)
+	}
+	var corners [4]int
+	for i, field := range fields[1:] {
+		number, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return zoneArea{}, err
+		}
+		corners[i] = number
+	}
+	return zoneArea{strings.TrimSpace(fields[0]), tile{corners[0], corners[1]}, tile{corners[2], corners[3]}}, nil
+}
+
+// currentRoom is filled by whichever loader read the room file
+var currentRoom roomDetails
+
+// loadRoom picks the right loader based on the file extension, csv is the default
+func (c *Cleaner) loadRoom(filePath string) [][]string {
+	currentRoom = roomDetails{}
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".json":
+		return c.readJsonFile(filePath)
+	case ".txt", ".map":
+		return c.readAsciiFile(filePath)
+	default:
+		return c.readCsvFile(filePath)
+	}
+}
+
+// saveRoom writes the room in the format matching the file extension, using the cleaner's
+// current location as the start
+func (c *Cleaner) saveRoom(filePath string, room [][]string) error {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".json":
+		return c.writeJsonFile(filePath, room)
+	case ".txt", ".map":
+		return c.writeAsciiFile(filePath, room)
+	default:
+		return c.writeCsvFile(filePath, room)
+	}
+}
+
+// jsonRoom is the layout of a JSON room description
+type jsonRoom struct {
+	Cleaner struct {
+		StartX         int `json:"start_x"`
+		StartY         int `json:"start_y"`
+		Battery        int `json:"battery"`
+		MovementEnergy int `json:"movement_energy"`
+		VacuumEnergy   int `json:"vacuum_energy"`
+	} `json:"cleaner"`
+	Grid  [][]int `json:"grid"`
+	Docks []struct {
+		X int `json:"x"`
+		Y int `json:"y"`
+	} `json:"docks,omitempty"`
+	Terrain [][]string `json:"terrain,omitempty"`
+	Zones   []jsonZone `json:"zones,omitempty"`
+}
+
+type jsonZone struct {
+	Name string `json:"name"`
+	X1   int    `json:"x1"`
+	Y1   int    `json:"y1"`
+	X2   int    `json:"x2"`
+	Y2   int    `json:"y2"`
+}
+
+func (c *Cleaner) readJsonFile(filePath string) [][]string {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		log.Fatal("Unable to read input file "+filePath, err)
+	}
+
+	var description jsonRoom
+	err = json.Unmarshal(data, &description)
+	if err != nil {
+		log.Fatal("Error reading json data ", err)
+	}
+
+	c.locationX = description.Cleaner.StartX
+	c.locationY = description.Cleaner.StartY
+	c.battery = description.Cleaner.Battery
+	c.movementEnergy = description.Cleaner.MovementEnergy
+	c.vacuumEnergy = description.Cleaner.VacuumEnergy
+
+	room := make([][]string, len(description.Grid))
+	for y, row := range description.Grid {
+		room[y] = make([]string, len(row))
+		for x, value := range row {
+			room[y][x] = strconv.Itoa(value)
+		}
+	}
+	for _, dock := range description.Docks {
+		currentRoom.docks = append(currentRoom.docks, tile{dock.X, dock.Y})
+	}
+	currentRoom.terrain = description.Terrain
+	for _, z := range description.Zones {
+		currentRoom.zones = append(currentRoom.zones, zoneArea{z.Name, tile{z.X1, z.Y1}, tile{z.X2, z.Y2}})
+	}
+	return room
+}
+
+func (c *Cleaner) writeJsonFile(filePath string, room [][]string) error {
+	var description jsonRoom
+	description.Cleaner.StartX = c.locationX
+	description.Cleaner.StartY = c.locationY
+	description.Cleaner.Battery = c.battery
+	description.Cleaner.MovementEnergy = c.movementEnergy
+	description.Cleaner.VacuumEnergy = c.vacuumEnergy
+
+	description.Grid = make([][]int, len(room))
+	for y, row := range room {
+		description.Grid[y] = make([]int, len(row))
+		for x, value := range row {
+			number, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return fmt.Errorf("tile %v has value %q: %w", tile{x, y}, value, err)
+			}
+			description.Grid[y][x] = number
+		}
+	}
+	for _, dock := range currentRoom.docks {
+		description.Docks = append(description.Docks, struct {
+			X int `json:"x"`
+			Y int `json:"y"`
+		}{dock.x, dock.y})
+	}
+	description.Terrain = currentRoom.terrain
+	for _, area := range currentRoom.zones {
+		description.Zones = append(description.Zones, jsonZone{area.name, area.from.x, area.from.y, area.to.x, area.to.y})
+	}
+
+	data, err := json.MarshalIndent(description, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, data, 0644)
+}
+
+func (c *Cleaner) writeCsvFile(filePath string, room [][]string) error {
+	var sb strings.Builder
+	for _, value := range []int{c.locationX, c.locationY, c.battery, c.movementEnergy, c.vacuumEnergy} {
+		sb.WriteString(strconv.Itoa(value))
+		sb.WriteString("\n")
+	}
+	for _, area := range currentRoom.zones {
+		fmt.Fprintf(&sb, "zone=%s,%d,%d,%d,%d\n", area.name, area.from.x, area.from.y, area.to.x, area.to.y)
+	}
+	for _, row := range room {
+		values := make([]string, len(row))
+		for x, value := range row {
+			values[x] = strings.TrimSpace(value)
+		}
+		sb.WriteString(strings.Join(values, ", "))
+		sb.WriteString("\n")
+	}
+	return os.WriteFile(filePath, []byte(sb.String()), 0644)
+}
+
+// ASCII maps are drawn by hand:
+//
+//	# wall, . clean floor, S start, D dock
+//	1-9 and a-z are dirt levels 1 to 35, every level is 10 dirt
+//
+// Lines of the form key=value before the map set the cleaner parameters
+// (battery, movement, vacuum) and can place the start or a dock on a dirty tile (start=x,y dock=x,y).
+// Dirt that is not a multiple of 10 up to 350 is given exactly as dirt=x,y,amount.
+// Named zones are rectangles given as zone=name,x1,y1,x2,y2, repeat the name to add more areas.
+// Lines starting with ; are comments. Short rows are padded with clean floor.
+const asciiDirtPerLevel = 10
+
+func asciiLevel(ch rune) (int, bool) {
+	switch {
+	case ch >= '1' && ch <= '9':
+		return int(ch - '0'), true
+	case ch >= 'a' && ch <= 'z':
+		return int(ch-'a') + 10, true
+	}
+	return 0, false
+}
+
+// asciiSymbol returns the symbol for a dirt value, rounding up so dirty tiles never save as clean.
+// exact is false when the symbol does not give the value back, the writer then adds a dirt= line.
+func asciiSymbol(dirt int) (symbol rune, exact bool) {
+	level := (dirt + asciiDirtPerLevel - 1) / asciiDirtPerLevel
+	exact = dirt == level*asciiDirtPerLevel
+	switch {
+	case level <= 0:
+		return '.', dirt == 0
+	case level <= 9:
+		return rune('0' + level), exact
+	case level <= 35:
+		return rune('a' + level - 10), exact
+	}
+	return 'z', false
+}
+
+// parseDirt reads x,y,amount as written after dirt= in ASCII maps
+func parseDirt(value string) (tile, int, error) {
+	fields := strings.Split(value, ",")
+	if len(fields) != 3 {
+		return tile{}, 0, fmt.Errorf("expected x,y,amount, got %q", value)
+	}
+	var numbers [3]int
+	for i, field := range fields {
+		number, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return tile{}, 0, err
+		}
+		numbers[i] = number
+	}
+	return tile{numbers[0], numbers[1]}, numbers[2], nil
+}
+
+func parseCoordinates(value string) (tile, error) {
+	return parseTile("(" + value + ")")
+}
+
+func (c *Cleaner) readAsciiFile(filePath string) [][]string {
+	f, err := os.Open(filePath)
+	if err != nil {
+		log.Fatal("Unable to read input file "+filePath, err)
+	}
+	defer func(f *os.File) {
+		err := f.Close()
+		if err != nil {
+			fmt.Println("Error closing file:", err)
+		}
+	}(f)
+
+	var room [][]string
+	exactDirt := map[tile]int{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// spaces are clean floor, so only the line ending is trimmed
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, ";") || (room == nil && strings.TrimSpace(line) == "") {
+			continue
+		}
+
+		if key, value, found := strings.Cut(line, "="); found && room == nil {
+			key = strings.TrimSpace(key)
+			value = strings.TrimSpace(value)
+			switch key {
+			case "zone":
+				area, err := parseZone(value)
+				if err != nil {
+					fmt.Println("Error reading zone:", err)
+					continue
+				}
+				currentRoom.zones = append(currentRoom.zones, area)
+			case "dirt":
+				position, amount, err := parseDirt(value)
+				if err != nil {
+					fmt.Println("Error reading dirt:", err)
+					continue
+				}
+				exactDirt[position] = amount
+			case "start", "dock":
+				position, err := parseCoordinates(value)
+				if err != nil {
+					fmt.Println("Error reading", key, "coordinates:", err)
+					continue
+				}
+				if key == "start" {
+					c.locationX, c.locationY = position.x, position.y
+				} else {
+					currentRoom.docks = append(currentRoom.docks, position)
+				}
+			default:
+				number, err := strconv.Atoi(value)
+				if err != nil {
+					fmt.Println("Error converting", key, "value to int:", err)
+					continue
+				}
+				switch key {
+				case "battery":
+					c.battery = number
+				case "movement":
+					c.movementEnergy = number
+				case "vacuum":
+					c.vacuumEnergy = number
+				default:
+					fmt.Println("Unknown map setting:", key)
+				}
+			}
+			continue
+		}
+
+		y := len(room)
+		row := make([]string, 0, len(line))
+		for x, ch := range []rune(line) {
+			switch ch {
+			case '#':
+				row = append(row, wallValue)
+			case '.', ' ':
+				row = append(row, "0")
+			case 'S':
+				c.locationX, c.locationY = x, y
+				row = append(row, "0")
+			case 'D':
+				currentRoom.docks = append(currentRoom.docks, tile{x, y})
+				row = append(row, "0")
+			default:
+				level, ok := asciiLevel(ch)
+				if !ok {
+					fmt.Printf("Unknown map symbol %q at (%d,%d), treating it as clean\n", ch, x, y)
+				}
+				row = append(row, strconv.Itoa(level*asciiDirtPerLevel))
+			}
+		}
+		room = append(room, row)
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatal("Error reading map data", err)
+	}
+
+	// rows of different lengths would let the moves index past the end of a short row
+	width := 0
+	for _, row := range room {
+		width = max(width, len(row))
+	}
+	for y := range room {
+		for len(room[y]) < width {
+			room[y] = append(room[y], "0")
+		}
+	}
+	for position, amount := range exactDirt {
+		if !inBounds(room, position.x, position.y) || isWall(room, position.x, position.y) {
+			fmt.Printf("Dirt setting for (%d,%d) is not on the floor, ignoring it\n", position.x, position.y)
+			continue
+		}
+		room[position.y][position.x] = strconv.Itoa(amount)
+	}
+	return room
+}
+
+// writeAsciiFile saves the room as an ASCII map. Dirt a symbol can't hold is written as a dirt= line
+// so it loads back exactly. Terrain has no symbols, a room with terrain has to be saved as JSON.
+func (c *Cleaner) writeAsciiFile(filePath string, room [][]string) error {
+	if currentRoom.terrain != nil {
+		return fmt.Errorf("ASCII maps can't hold terrain, save %s as .json instead", filePath)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "battery=%d\nmovement=%d\nvacuum=%d\n", c.battery, c.movementEnergy, c.vacuumEnergy)
+	for _, area := range currentRoom.zones {
+		fmt.Fprintf(&sb, "zone=%s,%d,%d,%d,%d\n", area.name, area.from.x, area.from.y, area.to.x, area.to.y)
+	}
+
+	symbols := make([][]rune, len(room))
+	for y := range room {
+		symbols[y] = make([]rune, len(room[y]))
+		for x := range room[y] {
+			if isWall(room, x, y) {
+				symbols[y][x] = '#'
+				continue
+			}
+			dirt := tileDirt(room, x, y)
+			symbol, exact := asciiSymbol(dirt)
+			symbols[y][x] = symbol
+			if !exact {
+				fmt.Fprintf(&sb, "dirt=%d,%d,%d\n", x, y, dirt)
+			}
+		}
+	}
+
+	// Markers can only go on clean tiles, anything else is written as a setting
+	for _, dock := range currentRoom.docks {
+		if inBounds(room, dock.x, dock.y) && symbols[dock.y][dock.x] == '.' {
+			symbols[dock.y][dock.x] = 'D'
+		} else {
+			fmt.Fprintf(&sb, "dock=%d,%d\n", dock.x, dock.y)
+		}
+	}
+	if inBounds(room, c.locationX, c.locationY) && symbols[c.locationY][c.locationX] == '.' {
+		symbols[c.locationY][c.locationX] = 'S'
+	} else {
+		fmt.Fprintf(&sb, "start=%d,%d\n", c.locationX, c.locationY)
+	}
+
+	for _, row := range symbols {
+		sb.WriteString(string(row))
+		sb.WriteString("\n")
+	}
+	return os.WriteFile(filePath, []byte(sb.String()), 0644)
+}
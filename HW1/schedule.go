@@ -0,0 +1,292 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// cleaningJob asks for every dirty tile of a zone to be cleaned inside a time window.
+// Higher priority jobs are kept when the battery can't cover everything.
+type cleaningJob struct {
+	zone     string
+	window   string // as written in the jobs file, for the report
+	earliest int
+	latest   int  // -1 when there is no deadline
+	last     bool // run after every other job
+	priority int
+
+	status   string // met, late or skipped
+	note     string
+	ran      bool
+	started  int
+	finished int
+	dirt     int
+}
+
+// readJobsFile loads one job per line as zone,window,priority where the window is
+// from-to in ticks (either side may be left out), any, or last
+func readJobsFile(filePath string) []*cleaningJob {
+	f, err := os.Open(filePath)
+	if err != nil {
+		log.Fatal("Unable to read jobs file "+filePath, err)
+	}
+	defer func(f *os.File) {
+		err := f.Close()
+		if err != nil {
+			fmt.Println("Error closing file:", err)
+		}
+	}(f)
+
+	csvReader := csv.NewReader(f)
+	csvReader.FieldsPerRecord = -1
+	csvReader.Comment = '#'
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		log.Fatal("Error reading jobs data", err)
+	}
+
+	var jobs []*cleaningJob
+	for _, record := range records {
+		if len(record) < 2 {
+			fmt.Println("Skipping job, expected zone,window,priority:", record)
+			continue
+		}
+		job := &cleaningJob{zone: strings.TrimSpace(record[0]), window: strings.TrimSpace(record[1]), latest: -1}
+		if len(record) > 2 {
+			job.priority, err = strconv.Atoi(strings.TrimSpace(record[2]))
+			if err != nil {
+				fmt.Println("Error converting job priority to int:", err)
+				continue
+			}
+		}
+
+		switch job.window {
+		case "", "any":
+			job.window = "any"
+		case "last":
+			job.last = true
+		default:
+			from, to, found := strings.Cut(job.window, "-")
+			if !found {
+				fmt.Println("Skipping job, window should be from-to, any or last:", record)
+				continue
+			}
+			if from = strings.TrimSpace(from); from != "" {
+				if job.earliest, err = strconv.Atoi(from); err != nil {
+					fmt.Println("Error converting job window to int:", err)
+					continue
+				}
+			}
+			if to = strings.TrimSpace(to); to != "" {
+				if job.latest, err = strconv.Atoi(to); err != nil {
+					fmt.Println("Error converting job window to int:", err)
+					continue
+				}
+			}
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+// zoneTour picks the dirty tiles of a zone nearest first from where the cleaner stands and
+// returns them with the number of moves the tour takes. Unreachable tiles are left out.
+func zoneTour(room [][]string, from tile, zone string) ([]tile, int) {
+	remaining := make(map[tile]bool)
+	for y := range room {
+		for x := range room[y] {
+			if tileDirt(room, x, y) > 0 && inZone(zone, tile{x, y}) {
+				remaining[tile{x, y}] = true
+			}
+		}
+	}
+
+	var tour []tile
+	moves := 0
+	current := from
+	for len(remaining) > 0 {
+		dist := distanceMap(room, current)
+		next, best := current, -1
+		for t := range remaining {
+			d := dist[t.y][t.x]
+			if d >= 0 && (best == -1 || d < best || (d == best && (t.y < next.y || (t.y == next.y && t.x < next.x)))) {
+				next, best = t, d
+			}
+		}
+		if best == -1 {
+			break
+		}
+		delete(remaining, next)
+		tour = append(tour, next)
+		moves += best
+		current = next
+	}
+	return tour, moves
+}
+
+// orderJobs sorts by deadline so tight windows go first, then by priority. Jobs without a deadline
+// follow the ones with one and the jobs marked last always come at the end.
+func orderJobs(jobs []*cleaningJob) []*cleaningJob {
+	ordered := append([]*cleaningJob(nil), jobs...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a, b := ordered[i], ordered[j]
+		if a.last != b.last {
+			return !a.last
+		}
+		if (a.latest == -1) != (b.latest == -1) {
+			return a.latest != -1
+		}
+		if a.latest != b.latest {
+			return a.latest < b.latest
+		}
+		return a.priority > b.priority
+	})
+	return ordered
+}
+
+// planSchedule asks the battery model what the jobs cost when done in order and drops
+// the lowest priority jobs until the rest fit in the battery
+func (c *Cleaner) planSchedule(room [][]string, jobs []*cleaningJob) []*cleaningJob {
+	var plan []*cleaningJob
+	for _, job := range orderJobs(jobs) {
+		found := false
+		for _, area := range currentRoom.zones {
+			found = found || area.name == job.zone
+		}
+		if !found {
+			job.status, job.note = "skipped", "unknown zone"
+			continue
+		}
+		plan = append(plan, job)
+	}
+
+	for len(plan) > 0 {
+		moves, vacuums := 0, 0
+		position := tile{c.locationX, c.locationY}
+		for _, job := range plan {
+			tour, tourMoves := zoneTour(room, position, job.zone)
+			moves += tourMoves
+			vacuums += len(tour)
+			if len(tour) > 0 {
+				position = tour[len(tour)-1]
+			}
+		}
+		if c.predictedCost(moves, vacuums) <= c.battery {
+			break
+		}
+
+		drop := 0
+		for i, job := range plan {
+			if job.priority <= plan[drop].priority {
+				drop = i
+			}
+		}
+		plan[drop].status, plan[drop].note = "skipped", fmt.Sprintf("battery %d too low for the whole plan", c.battery)
+		plan = append(plan[:drop], plan[drop+1:]...)
+	}
+	return plan
+}
+
+// stepToward moves one tile along the shortest path and only vacuums tiles of the zone being cleaned,
+// so zones with a window aren't cleaned early by passing through them
+func (c *Cleaner) stepToward(room [][]string, target tile, zone string) bool {
+	path := shortestPath(room, tile{c.locationX, c.locationY}, target)
+	if len(path) == 0 {
+		return false
+	}
+	c.moveSomewhere(path[0].String(), room)
+	if c.locationX != path[0].x || c.locationY != path[0].y {
+		return false
+	}
+	if inZone(zone, path[0]) {
+		c.decideToClean(room)
+	}
+	return true
+}
+
+// cleanWithSchedule runs the jobs one zone at a time, waiting for a window to open when the cleaner
+// would arrive too early
+func (c *Cleaner) cleanWithSchedule(roomData [][]string, jobs []*cleaningJob) []string {
+	totalPath := []string{tile{c.locationX, c.locationY}.String()}
+	c.recordTrail()
+	for _, job := range c.planSchedule(roomData, jobs) {
+		tour, _ := zoneTour(roomData, tile{c.locationX, c.locationY}, job.zone)
+		if len(tour) == 0 {
+			job.status, job.note = "met", "nothing to clean"
+			if zoneHasDirt(roomData, job.zone) {
+				job.status, job.note = "skipped", "dirty tiles can't be reached"
+			}
+			continue
+		}
+
+		travel := distanceMap(roomData, tile{c.locationX, c.locationY})[tour[0].y][tour[0].x]
+		for c.ticks+travel < job.earliest {
+			c.wait()
+		}
+		job.ran = true
+
+		dirtBefore := c.dirtVolume
+		stuck := false
+		for i, target := range tour {
+			for !stuck && (c.locationX != target.x || c.locationY != target.y) {
+				if !c.stepToward(roomData, target, job.zone) {
+					stuck = true
+				} else {
+					totalPath = append(totalPath, tile{c.locationX, c.locationY}.String())
+				}
+			}
+			if stuck {
+				break
+			}
+			if i == 0 {
+				job.started = c.ticks
+			}
+			c.decideToClean(roomData)
+		}
+		job.finished = c.ticks
+		job.dirt = c.dirtVolume - dirtBefore
+
+		// Whatever the tour can still reach was left behind for lack of battery
+		left, _ := zoneTour(roomData, tile{c.locationX, c.locationY}, job.zone)
+		switch {
+		case stuck || len(left) > 0:
+			job.status, job.note = "skipped", "battery ran out during the job"
+		case job.latest != -1 && job.finished > job.latest:
+			job.status, job.note = "late", fmt.Sprintf("%d ticks past the window", job.finished-job.latest)
+		default:
+			job.status = "met"
+		}
+	}
+	return totalPath
+}
+
+func zoneHasDirt(room [][]string, zone string) bool {
+	for y := range room {
+		for x := range room[y] {
+			if tileDirt(room, x, y) > 0 && inZone(zone, tile{x, y}) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func printSchedule(jobs []*cleaningJob) {
+	fmt.Println("Schedule report:")
+	fmt.Printf("%-12s %-10s %8s %8s %8s %8s %6s  %s\n", "zone", "window", "priority", "status", "started", "finished", "dirt", "note")
+	counts := make(map[string]int)
+	for _, job := range jobs {
+		counts[job.status]++
+		if !job.ran {
+			fmt.Printf("%-12s %-10s %8d %8s %8s %8s %6s  %s\n", job.zone, job.window, job.priority, job.status, "-", "-", "-", job.note)
+			continue
+		}
+		fmt.Printf("%-12s %-10s %8d %8s %8d %8d %6d  %s\n", job.zone, job.window, job.priority, job.status, job.started, job.finished, job.dirt, job.note)
+	}
+	fmt.Printf("Jobs met: %d, late: %d, skipped: %d\n", counts["met"], counts["late"], counts["skipped"])
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Sensor and belief settings that don't get their own flag
+const (
+	sensorFalseAlarm = 0.02 // chance a clean tile reads as dirty
+	beliefPriorDirty = 0.5  // chance a tile we know nothing about is dirty
+	beliefPriorMean  = 100  // dirt we expect on a dirty tile before seeing it
+	beliefPriorSD    = 1000
+	vacuumThreshold  = 0.5 // vacuum a tile once it is more likely dirty than not
+	resenseLow       = 0.2 // re-sense the current tile while its belief is between these two
+	resenseHigh      = 0.8
+	maxResenses      = 3
+)
+
+// dirtSensor reads the dirt around the cleaner. With noise and miss rate both zero it reads exact values.
+type dirtSensor struct {
+	noise    float64 // standard deviation of the reading
+	missRate float64 // chance a dirty tile reads as clean
+	radius   int     // tiles within this many moves are read, 0 is only the tile below the cleaner
+	rng      *rand.Rand
+}
+
+// read returns what the sensor sees on a tile, never less than zero
+func (s *dirtSensor) read(room [][]string, t tile) float64 {
+	truth := float64(tileDirt(room, t.x, t.y))
+	if truth <= 0 {
+		if s.rng.Float64() < sensorFalseAlarm {
+			return math.Round(math.Abs(s.rng.NormFloat64() * s.noise))
+		}
+		return 0
+	}
+	if s.rng.Float64() < s.missRate {
+		return 0
+	}
+	return math.Max(1, math.Round(truth+s.rng.NormFloat64()*s.noise))
+}
+
+// tileBelief is the probability that a tile is dirty and, if it is, a normal estimate of how much
+type tileBelief struct {
+	dirty    float64
+	mean     float64
+	variance float64
+	sensed   bool
+	vacuumed bool
+	found    int // dirt collected when vacuumed
+}
+
+func (b tileBelief) expectedDirt() float64 {
+	return b.dirty * b.mean
+}
+
+func normalDensity(x, mean, variance float64) float64 {
+	return math.Exp(-(x-mean)*(x-mean)/(2*variance)) / math.Sqrt(2*math.Pi*variance)
+}
+
+// observe applies Bayes' rule for one reading. A zero reading is either a clean tile or a miss,
+// a positive one either a false alarm or the dirt plus noise, which also narrows the amount.
+func (b *tileBelief) observe(reading float64, s *dirtSensor) {
+	noiseVariance := math.Max(s.noise*s.noise, 1)
+	var likelihoodDirty, likelihoodClean float64
+	if reading == 0 {
+		likelihoodDirty, likelihoodClean = s.missRate, 1-sensorFalseAlarm
+	} else {
+		likelihoodDirty = (1 - s.missRate) * normalDensity(reading, b.mean, b.variance+noiseVariance)
+		likelihoodClean = sensorFalseAlarm * 2 * normalDensity(reading, 0, noiseVariance)
+	}
+	evidence := b.dirty*likelihoodDirty + (1-b.dirty)*likelihoodClean
+	if evidence > 0 {
+		b.dirty = b.dirty * likelihoodDirty / evidence
+	}
+	if reading > 0 {
+		// Kalman update of the amount, assuming the reading came from dirt
+		gain := b.variance / (b.variance + noiseVariance)
+		b.mean += gain * (reading - b.mean)
+		b.variance *= 1 - gain
+	}
+}
+
+// beliefMap is what the cleaner thinks is on the floor, walls are known from the room map
+type beliefMap struct {
+	tiles  map[tile]*tileBelief
+	sensor *dirtSensor
+	senses int
+}
+
+// newBeliefMap starts every floor tile from the prior, learned from earlier runs when history is not nil
+func newBeliefMap(room [][]string, sensor *dirtSensor, history *roomHistory) *beliefMap {
+	m := &beliefMap{tiles: make(map[tile]*tileBelief), sensor: sensor}
+	for y := range room {
+		for x := range room[y] {
+			if !isBlocked(room, x, y) {
+				prior := history.prior(tile{x, y})
+				m.tiles[tile{x, y}] = &prior
+			}
+		}
+	}
+	return m
+}
+
+// observed is the dirt the cleaner found on every tile it sensed or vacuumed, to learn from
+func (m *beliefMap) observed() map[tile]int {
+	result := make(map[tile]int)
+	for t, b := range m.tiles {
+		switch {
+		case b.vacuumed:
+			result[t] = b.found
+		case b.sensed && b.dirty >= vacuumThreshold:
+			result[t] = int(math.Round(b.mean))
+		case b.sensed:
+			result[t] = 0
+		}
+	}
+	return result
+}
+
+// sense reads every tile within the sensor radius of the cleaner
+func (m *beliefMap) sense(room [][]string, at tile) {
+	m.senses++
+	for t, b := range m.tiles {
+		if abs(t.x-at.x)+abs(t.y-at.y) <= m.sensor.radius {
+			b.observe(m.sensor.read(room, t), m.sensor)
+			b.sensed = true
+		}
+	}
+}
+
+// target picks the reachable tile with the most expected dirt among those worth vacuuming,
+// the closer one wins a tie like in the AStar loop
+func (m *beliefMap) target(room [][]string, from tile) (tile, bool) {
+	dist := distanceMap(room, from)
+	var best tile
+	found := false
+	for t, b := range m.tiles {
+		if b.dirty < vacuumThreshold || dist[t.y][t.x] < 0 {
+			continue
+		}
+		if !found {
+			best, found = t, true
+			continue
+		}
+		current := m.tiles[best]
+		switch {
+		case b.expectedDirt() > current.expectedDirt():
+			best = t
+		case b.expectedDirt() == current.expectedDirt() && dist[t.y][t.x] < dist[best.y][best.x]:
+			best = t
+		case b.expectedDirt() == current.expectedDirt() && dist[t.y][t.x] == dist[best.y][best.x] && (t.y < best.y || (t.y == best.y && t.x < best.x)):
+			best = t
+		}
+	}
+	return best, found
+}
+
+// beliefStats counts the decisions the cleaner made from its belief
+type beliefStats struct {
+	resenses      int
+	wastedVacuums int // vacuumed a tile that turned out clean
+}
+
+// cleanWithBelief only knows the dirt through the sensor. It senses after every move, heads for the
+// tile with the most expected dirt and vacuums once a tile is more likely dirty than not. When the
+// tile below it is uncertain it senses again first, a reading costs a tick but no battery.
+func (c *Cleaner) cleanWithBelief(roomData [][]string, belief *beliefMap) ([]string, beliefStats) {
+	var stats beliefStats
+	totalPath := []string{tile{c.locationX, c.locationY}.String()}
+	c.recordTrail()
+	belief.sense(roomData, tile{c.locationX, c.locationY})
+	resensed := make(map[tile]int)
+	for c.battery > 0 {
+		here := tile{c.locationX, c.locationY}
+		b := belief.tiles[here]
+		for b != nil && b.dirty > resenseLow && b.dirty < resenseHigh && resensed[here] < maxResenses {
+			c.wait()
+			belief.sense(roomData, here)
+			resensed[here]++
+			stats.resenses++
+		}
+		if b != nil && b.dirty >= vacuumThreshold && c.battery >= c.cost(c.vacuumEnergy) {
+			truth := tileDirt(roomData, here.x, here.y)
+			c.clean(roomData)
+			roomData[here.y][here.x] = "0"
+			if truth <= 0 {
+				stats.wastedVacuums++
+			}
+			// Vacuuming tells us exactly what was there, the tile is clean now
+			b.dirty, b.mean, b.variance = 0, float64(max(truth, 0)), 0
+			b.vacuumed, b.found = true, max(truth, 0)
+			continue
+		}
+
+		goal, ok := belief.target(roomData, here)
+		if !ok {
+			fmt.Println("No more tiles believed to be dirty.")
+			break
+		}
+		if goal == here {
+			fmt.Println("Not enough battery to vacuum the current tile.")
+			break
+		}
+		next := shortestPath(roomData, here, goal)[0]
+		c.moveSomewhere(next.String(), roomData)
+		if c.locationX != next.x || c.locationY != next.y {
+			fmt.Println("Not enough battery to reach the tiles believed to be dirty.")
+			break
+		}
+		totalPath = append(totalPath, next.String())
+		belief.sense(roomData, next)
+	}
+	return totalPath, stats
+}
+
+// print compares what the cleaner believes with the dirt actually left on the floor
+func (m *beliefMap) print(room [][]string, stats beliefStats) {
+	fmt.Println("Belief report:")
+	fmt.Printf("Sensor noise %.1f, miss rate %.2f, radius %d\n", m.sensor.noise, m.sensor.missRate, m.sensor.radius)
+	fmt.Println("Sensings:", m.senses, "re-senses:", stats.resenses, "vacuums on clean tiles:", stats.wastedVacuums)
+
+	var tiles []tile
+	for t := range m.tiles {
+		tiles = append(tiles, t)
+	}
+	sort.Slice(tiles, func(i, j int) bool {
+		return tiles[i].y < tiles[j].y || (tiles[i].y == tiles[j].y && tiles[i].x < tiles[j].x)
+	})
+
+	var brier, absError float64
+	missed, phantom := 0, 0
+	for _, t := range tiles {
+		b := m.tiles[t]
+		truth := tileDirt(room, t.x, t.y)
+		dirty := 0.0
+		if truth > 0 {
+			dirty = 1
+		}
+		brier += (b.dirty - dirty) * (b.dirty - dirty)
+		absError += math.Abs(b.expectedDirt() - float64(max(truth, 0)))
+		switch {
+		case truth > 0 && b.dirty < vacuumThreshold:
+			missed++
+			fmt.Printf("  %v has dirt %d but is believed clean (p=%.2f)\n", t, truth, b.dirty)
+		case truth <= 0 && b.dirty >= vacuumThreshold:
+			phantom++
+			fmt.Printf("  %v is clean but believed dirty (p=%.2f)\n", t, b.dirty)
+		}
+	}
+	if len(tiles) > 0 {
+		brier /= float64(len(tiles))
+		absError /= float64(len(tiles))
+	}
+	fmt.Printf("Tiles believed clean but dirty: %d, believed dirty but clean: %d\n", missed, phantom)
+	fmt.Printf("Brier score of P(dirty): %.3f, mean absolute error of expected dirt: %.1f\n", brier, absError)
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// statePlanner searches over full cleaner states: where the cleaner is, what is left in the battery
+// and which dirty tiles it already vacuumed. A plan ends with a stop that costs the dirt still on the
+// floor times one more than the starting battery, so the cheapest plan collects the most dirt the
+// battery allows and uses the least energy doing it. No energy spent can outweigh a single unit of dirt.
+type statePlanner struct {
+	room     [][]string
+	dirty    []tile       // dirty tiles, bit i of a mask is dirty[i]
+	index    map[tile]int // dirty tile to bit
+	distance [][][]int    // walking distance from every dirty tile, like distanceMap
+	cleaner  Cleaner
+	capacity int
+	weight   int // cost of one unit of dirt left behind
+
+	algorithm     string
+	maxExpansions int
+	maxStates     int
+	expanded      int
+	stored        int
+	limitHit      bool
+}
+
+type stateKey struct {
+	at   tile
+	mask uint64
+}
+
+type stateNode struct {
+	stateKey
+	battery int
+	g, f    int
+	stopped bool
+	action  pddlAction
+	parent  *stateNode
+}
+
+type stateQueue []*stateNode
+
+func (q stateQueue) Len() int { return len(q) }
+func (q stateQueue) Less(i, j int) bool {
+	return q[i].f < q[j].f || (q[i].f == q[j].f && q[i].g > q[j].g)
+}
+func (q stateQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *stateQueue) Push(x interface{}) { *q = append(*q, x.(*stateNode)) }
+func (q *stateQueue) Pop() interface{} {
+	old := *q
+	node := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return node
+}
+
+func newStatePlanner(c *Cleaner, room [][]string, algorithm string, maxExpansions, maxStates int) (*statePlanner, error) {
+	p := &statePlanner{
+		room: room, index: make(map[tile]int), cleaner: *c, capacity: c.fullCharge(), weight: c.battery + 1,
+		algorithm: algorithm, maxExpansions: maxExpansions, maxStates: maxStates,
+	}
+	for y := range room {
+		for x := range room[y] {
+			if tileDirt(room, x, y) > 0 && !isBlocked(room, x, y) {
+				p.index[tile{x, y}] = len(p.dirty)
+				p.dirty = append(p.dirty, tile{x, y})
+			}
+		}
+	}
+	if len(p.dirty) > 64 {
+		return nil, fmt.Errorf("the room has %d dirty tiles, the state-space planner handles at most 64", len(p.dirty))
+	}
+	for _, t := range p.dirty {
+		p.distance = append(p.distance, distanceMap(room, t))
+	}
+	return p, nil
+}
+
+// dirtLeft is the dirt on the tiles the mask hasn't vacuumed yet
+func (p *statePlanner) dirtLeft(mask uint64) int {
+	total := 0
+	for i, t := range p.dirty {
+		if mask&(1<<i) == 0 {
+			total += tileDirt(p.room, t.x, t.y)
+		}
+	}
+	return total
+}
+
+// heuristic never overestimates the cost still to come. A plan either cleans every reachable tile left,
+// which needs at least the walk to the closest one plus a minimum spanning tree over the rest (the relaxed
+// MST bound), or it leaves some dirt behind, at least what the battery can't possibly collect.
+func (p *statePlanner) heuristic(n *stateNode) int {
+	if n.stopped {
+		return 0
+	}
+	var left []int
+	dirt, smallest := 0, -1
+	for i := range p.dirty {
+		if n.mask&(1<<i) != 0 {
+			continue
+		}
+		d := tileDirt(p.room, p.dirty[i].x, p.dirty[i].y)
+		dirt += d
+		if p.distance[i][n.at.y][n.at.x] < 0 {
+			continue // can never be collected, it is always left behind
+		}
+		left = append(left, i)
+		if smallest == -1 || d < smallest {
+			smallest = d
+		}
+	}
+	unreachable := dirt
+	for _, i := range left {
+		unreachable -= tileDirt(p.room, p.dirty[i].x, p.dirty[i].y)
+	}
+	if len(left) == 0 {
+		return p.weight * unreachable
+	}
+
+	// Prim's algorithm over the tiles left, starting from the cleaner
+	closest := -1
+	for _, i := range left {
+		if d := p.distance[i][n.at.y][n.at.x]; closest == -1 || d < closest {
+			closest = d
+		}
+	}
+	moves := closest
+	inTree := make([]bool, len(left))
+	cheapest := make([]int, len(left))
+	for k := range left {
+		cheapest[k] = -1
+	}
+	current := -1
+	for range left {
+		if current == -1 {
+			// Root the tree at the tile closest to the cleaner
+			for k, i := range left {
+				if p.distance[i][n.at.y][n.at.x] == closest {
+					current = k
+					break
+				}
+			}
+		} else {
+			current = -1
+			for k := range left {
+				if !inTree[k] && (current == -1 || cheapest[k] < cheapest[current]) {
+					current = k
+				}
+			}
+			moves += cheapest[current]
+		}
+		inTree[current] = true
+		from := p.dirty[left[current]]
+		for k, i := range left {
+			if d := p.distance[i][from.y][from.x]; !inTree[k] && (cheapest[k] == -1 || d < cheapest[k]) {
+				cheapest[k] = d
+			}
+		}
+	}
+	cleanAll := -1
+	if energy := moves*p.cleaner.movementEnergy + len(left)*p.cleaner.vacuumEnergy; energy <= n.battery {
+		cleanAll = energy + p.weight*unreachable
+	}
+
+	// Leaving something: the battery allows at most k more vacuums, k-1 walks between them and the closest walk
+	var amounts []int
+	for _, i := range left {
+		amounts = append(amounts, tileDirt(p.room, p.dirty[i].x, p.dirty[i].y))
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(amounts)))
+	collectible := 0
+	for k := 1; k < len(left); k++ {
+		if closest*p.cleaner.movementEnergy+k*p.cleaner.vacuumEnergy+(k-1)*p.cleaner.movementEnergy > n.battery {
+			break
+		}
+		collectible += amounts[k-1]
+	}
+	leaveSome := p.weight * (unreachable + max(dirt-unreachable-collectible, smallest))
+
+	if cleanAll == -1 || leaveSome < cleanAll {
+		return leaveSome
+	}
+	return cleanAll
+}
+
+// successors are the moves to open neighbours, vacuuming the current tile and stopping
+func (p *statePlanner) successors(n *stateNode) []*stateNode {
+	if n.stopped {
+		return nil
+	}
+	var result []*stateNode
+	result = append(result, &stateNode{stateKey: n.stateKey, battery: n.battery, g: n.g + p.weight*p.dirtLeft(n.mask), stopped: true, parent: n})
+
+	if i, ok := p.index[n.at]; ok && n.mask&(1<<i) == 0 {
+		if drain := activeBattery.drain(n.battery, p.capacity, p.cleaner.vacuumEnergy); drain <= n.battery {
+			result = append(result, &stateNode{
+				stateKey: stateKey{n.at, n.mask | 1<<i}, battery: n.battery - drain, g: n.g + drain,
+				action: pddlAction{name: "vacuum", args: []string{pddlTile(n.at)}}, parent: n,
+			})
+		}
+	}
+	if drain := activeBattery.drain(n.battery, p.capacity, p.cleaner.movementEnergy); drain <= n.battery {
+		for _, next := range openNeighbors(p.room, n.at) {
+			result = append(result, &stateNode{
+				stateKey: stateKey{next, n.mask}, battery: n.battery - drain, g: n.g + drain,
+				action: pddlAction{name: "move", args: []string{pddlTile(n.at), pddlTile(next)}}, parent: n,
+			})
+		}
+	}
+	return result
+}
+
+func (p *statePlanner) priority(n *stateNode) int {
+	switch p.algorithm {
+	case "ucs":
+		return n.g
+	case "best":
+		// Stopping ranks by the dirt it leaves behind, otherwise it would always look best
+		if n.stopped {
+			return n.g - n.parent.g
+		}
+		return p.heuristic(n)
+	}
+	return n.g + p.heuristic(n)
+}
+
+// search runs best-first, uniform-cost or A* and returns the stopped node of the plan, nil if a limit
+// was hit first. Two ways to the same place and mask only keep the cheaper one, since spending less
+// energy also leaves more battery.
+func (p *statePlanner) search(start *stateNode) *stateNode {
+	if p.algorithm == "idastar" {
+		return p.idaStar(start)
+	}
+	best := map[stateKey]int{start.stateKey: 0}
+	start.f = p.priority(start)
+	queue := &stateQueue{start}
+	for queue.Len() > 0 {
+		current := heap.Pop(queue).(*stateNode)
+		if current.stopped {
+			return current
+		}
+		if current.g > best[current.stateKey] {
+			continue
+		}
+		if p.expanded >= p.maxExpansions || len(best) >= p.maxStates {
+			p.limitHit = true
+			return nil
+		}
+		p.expanded++
+		for _, next := range p.successors(current) {
+			if !next.stopped {
+				if old, ok := best[next.stateKey]; ok && old <= next.g {
+					continue
+				}
+				best[next.stateKey] = next.g
+			}
+			next.f = p.priority(next)
+			heap.Push(queue, next)
+		}
+		p.stored = max(p.stored, len(best))
+	}
+	return nil
+}
+
+// idaStar repeats a depth first search with a growing bound on g+h, keeping only the current path in memory
+func (p *statePlanner) idaStar(start *stateNode) *stateNode {
+	bound := p.heuristic(start)
+	onPath := map[stateKey]bool{start.stateKey: true}
+	// depth is the number of nodes on the path down to n, which is all IDA* keeps in memory
+	var visit func(n *stateNode, depth int) (*stateNode, int)
+	visit = func(n *stateNode, depth int) (*stateNode, int) {
+		f := n.g + p.heuristic(n)
+		if f > bound {
+			return nil, f
+		}
+		if n.stopped {
+			return n, f
+		}
+		if p.expanded >= p.maxExpansions {
+			p.limitHit = true
+			return nil, -1
+		}
+		p.expanded++
+		p.stored = max(p.stored, depth)
+		smallest := -1
+		for _, next := range p.successors(n) {
+			// Stopping keeps the parent's state, so it is neither a cycle nor a new key on the path
+			if !next.stopped {
+				if onPath[next.stateKey] {
+					continue
+				}
+				onPath[next.stateKey] = true
+			}
+			found, over := visit(next, depth+1)
+			if !next.stopped {
+				delete(onPath, next.stateKey)
+			}
+			if found != nil || p.limitHit {
+				return found, over
+			}
+			if smallest == -1 || over < smallest {
+				smallest = over
+			}
+		}
+		return nil, smallest
+	}
+	for {
+		found, next := visit(start, 1)
+		if found != nil || p.limitHit || next == -1 {
+			return found
+		}
+		bound = next
+	}
+}
+
+// optimalPlan searches from the cleaner's current state and returns the plan as PDDL actions
+// so the simulator can check it like any imported plan
+func (p *statePlanner) optimalPlan() ([]pddlAction, int, bool) {
+	start := &stateNode{stateKey: stateKey{tile{p.cleaner.locationX, p.cleaner.locationY}, 0}, battery: p.cleaner.battery}
+	goal := p.search(start)
+	if goal == nil {
+		return nil, 0, false
+	}
+	var plan []pddlAction
+	for n := goal.parent; n != nil && n.parent != nil; n = n.parent {
+		plan = append([]pddlAction{n.action}, plan...)
+	}
+	return plan, goal.g, true
+}
+
+// compareWithOptimal runs the state-space planner and the greedy AStar loop from the same room and
+// prints how far the greedy loop is from the best possible run
+func (c *Cleaner) compareWithOptimal(roomData [][]string, algorithm string, maxExpansions, maxStates int) []string {
+	greedyCleaner := *c
+	greedyStats, _ := greedyCleaner.measureRun("greedy", copyRoom(roomData), (*Cleaner).cleanWithAStar)
+
+	planner, err := newStatePlanner(c, roomData, algorithm, maxExpansions, maxStates)
+	if err != nil {
+		fmt.Println("State-space planner:", err)
+		return nil
+	}
+	begin := time.Now()
+	plan, cost, ok := planner.optimalPlan()
+	elapsed := time.Since(begin)
+	fmt.Printf("State-space search (%s): %d states expanded, %d stored at most, %v\n", algorithm, planner.expanded, planner.stored, elapsed)
+	if !ok {
+		if planner.limitHit {
+			fmt.Println("Search stopped at the expansion or state limit, no plan found")
+		} else {
+			fmt.Println("Search found no plan")
+		}
+		return nil
+	}
+	if algorithm == "best" {
+		fmt.Println("Best-first search is not guaranteed to be optimal")
+	}
+	fmt.Printf("Plan with %d actions, cost %d (%d dirt left, %d energy)\n", len(plan), cost, cost/planner.weight, cost%planner.weight)
+
+	var path []string
+	planStats, _ := c.measureRun(algorithm, roomData, func(c *Cleaner, room [][]string) []string {
+		path, err = c.executePlan(room, plan)
+		return path
+	})
+	if err != nil {
+		fmt.Println("Plan rejected by the simulator:", err)
+	}
+	printComparison([]cleaningStats{planStats, greedyStats})
+	return path
+}
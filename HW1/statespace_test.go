@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+// TestOptimalPlannersAgree runs each state-space search on room.csv, they must all find the
+// optimal cost and a plan the simulator accepts
+func TestOptimalPlannersAgree(t *testing.T) {
+	for _, algorithm := range []string{"astar", "ucs", "idastar"} {
+		t.Run(algorithm, func(t *testing.T) {
+			c, room := testCleaner(t)
+			planner, err := newStatePlanner(c, room, algorithm, 5000000, 2000000)
+			if err != nil {
+				t.Fatal(err)
+			}
+			plan, cost, ok := planner.optimalPlan()
+			if !ok {
+				t.Fatal("no plan found")
+			}
+			if cost != 27 {
+				t.Errorf("the plan costs %d, want 27", cost)
+			}
+			if _, err := c.executePlan(room, plan); err != nil {
+				t.Errorf("the simulator refuses the plan: %v", err)
+			}
+		})
+	}
+}
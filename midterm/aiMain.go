@@ -1,23 +1,20 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io"
+	"log"
+	"math"
 	"net/http"
+	"os"
+	"os/signal"
 	"time"
 	"flag"
 )
-var url string
-func init() {
-	// parse the command line argument for url
-	flag.StringVar(&url, "url", "http://37.27.208.205:55555", "API URL")
-	flag.Parse()
-}
 // var url = "https://gomoku.martinsp.org/"
 // var url = "http://37.27.208.205:55555"
 var student_id = "221RDB477"
-var depth = 2
 // Game represents the game state from the API response
 type Game struct {
 	Color         string  `json:"color"`
@@ -29,179 +26,175 @@ type Game struct {
 	RequestStatus string  `json:"request_status"`
 }
 
-// sendStartRequest sends a GET request to the specified endpoint and loads response data into the Game struct
-func (g *Game) sendStartRequest() error {
-	endpoint := fmt.Sprintf("%s/%s/start",url  ,student_id)
-	response, err := http.Get(endpoint)
-	if err != nil {
-		fmt.Println("Error sending request:", err)
-		return err
-	}
-	defer response.Body.Close()
 
-	body, err := io.ReadAll(response.Body)
+func main() {
+	cfg, err := parseConfig(flag.CommandLine, os.Args[1:])
 	if err != nil {
-		fmt.Println("Error reading response:", err)
-		return err
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
 	}
-
-	fmt.Println("Response status:", response.Status)
-	fmt.Println("Response body:", string(body))
-
-	// Unmarshal the JSON response directly into the Game struct
-	err = json.Unmarshal(body, g)
-	if err != nil {
-		fmt.Println("Error unmarshalling response:", err)
-		return err
+	if cfg.serveAddr != "" {
+		fmt.Println("Local game server listening on", cfg.serveAddr)
+		log.Fatal(http.ListenAndServe(cfg.serveAddr, newGameServer(time.Now().UnixNano(), cfg.server)))
 	}
-
-	return nil
-}
-
-func (g *Game) move(moveX int, moveY int) error {
-
-	endpoint := fmt.Sprintf("%s/%s/%d/%d/%d", url,student_id, g.GameID, moveX, moveY)
-	response, err := http.Get(endpoint)
-
-	if err != nil {
-		fmt.Println("Error sending request:", err)
-		return err
+	if cfg.solvePath != "" {
+		solvePosition(cfg.solvePath, cfg.search)
+		return
 	}
-	defer response.Body.Close()
-
-	// update the game state with the new data
-	body, err := io.ReadAll(response.Body)
-	if err != nil {
-		fmt.Println("Error reading response:", err)
-		return err
+	if cfg.benchTT {
+		runBenchTT(cfg.search, cfg.server.boardSize)
+		return
 	}
-
-	fmt.Println("Response status:", response.Status)
-	fmt.Println("Response body:", string(body))
-	if response.Status != "200 OK" {
-		return fmt.Errorf("Move request failed: %s", response.Status)
+	if cfg.benchOrdering {
+		runBenchOrdering(cfg.search, cfg.server.boardSize)
+		return
 	}
-
-	// Check the response status
-	if response.StatusCode == 400 {
-		fmt.Println("ERROR: Invalid move detected!")
-		return fmt.Errorf("invalid move: %s", string(body))
+	if cfg.benchBoard {
+		runBenchBoard(cfg.search, cfg.server.boardSize)
+		return
 	}
-
-	// Unmarshal the JSON response directly into the Game struct
-	err = json.Unmarshal(body, g)
+	if cfg.matchGames > 0 {
+		runMatch(cfg.matchGames, cfg.matchMoveTime, cfg.search, cfg.server.boardSize)
+		return
+	}
+	engine, err := newEngine(cfg.engine, cfg.search)
 	if err != nil {
-		fmt.Println("Error unmarshalling response:", err)
-		return err
+		fmt.Println("Error choosing the engine:", err)
+		os.Exit(1)
+	}
+	url := cfg.url
+	if cfg.local {
+		localURL, err := startLocalServer(newGameServer(time.Now().UnixNano(), cfg.server))
+		if err != nil {
+			log.Fatal("Failed to start the local server: ", err)
+		}
+		url = localURL
+		fmt.Println("Playing on the local server at", url)
 	}
 
-	// Print game data to verify it was correctly loaded
-	fmt.Println("Game data updated successfully:")
-	fmt.Printf("Color: %s\n", g.Color)
-	fmt.Printf("Game ID: %d\n", g.GameID)
-	fmt.Printf("Game Status: %s\n", g.GameStatus)
-	fmt.Printf("Time Remaining: %.1f\n", g.TimeRemaining)
-	fmt.Printf("Turn: %s\n", g.Turn)
-
-	return nil
-}
-
-var globalBoard = [][]int{
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	client := newHTTPClient(url, student_id, cfg.client.timeout, cfg.client.retries, cfg.client.backoff)
+	if err := playGames(ctx, client, engineMove(engine, cfg.search)); err != nil {
+		fmt.Println("Stopped playing:", err)
+	}
 }
 
-func give_correct_board() [][]int {
-	return globalBoard
+// engineMove is how the game loop asks the engine for a move, to a fixed depth or within the
+// time the clock allows
+func engineMove(engine Engine, cfg searchConfig) func(Game) Point {
+	return func(game Game) Point {
+		// Print board with proper indexing for better visualization
+		board := convertGameboard(game.Gameboard)
+		printBoardWithIndexing(board)
+		maxDepth, deadline := cfg.depth, time.Time{}
+		if !cfg.fixedDepth {
+			budget := moveBudget(game.TimeRemaining, countStones(board, toPlayer(game.Color)), cfg)
+			fmt.Printf("Thinking for up to %v with %.1fs on the clock\n", budget, game.TimeRemaining)
+			maxDepth, deadline = cfg.maxDepth, time.Now().Add(budget)
+		}
+		move, err := engine.findBestMove(board, game.Color, maxDepth, deadline)
+		if err != nil {
+			fmt.Println("The engine could not move, playing the fallback move instead:", err)
+			return fallbackMove(board)
+		}
+		return move
+	}
 }
 
+// playGames keeps starting games until the server tells us to leave
+func playGames(ctx context.Context, client Client, think func(Game) Point) error {
+	for {
+		game, err := client.Start(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to initialize game: %w", err)
+		}
+		if game.GameStatus == "LEAVE" {
+			fmt.Println("Exiting game...")
+			return nil
+		}
+		fmt.Println("Game initialized successfully!")
 
-func main() {
-	startNewGame()
+		game, err = playGame(ctx, client, game, think)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Game is finished with status:", game.GameStatus)
+		fmt.Println("Starting new game...")
+	}
 }
+
 var lastRequestTime time.Time
-func startNewGame() {
-	newGame := Game{}
 
-	err := newGame.sendStartRequest()
-	if err != nil {
-		fmt.Println("Failed to initialize game:", err)
-		return
+// playGame polls the game and plays whenever it is our turn, until the game is no longer ongoing
+func playGame(ctx context.Context, client Client, game Game, think func(Game) Point) (Game, error) {
+	for game.GameStatus == "ONGOING" {
+		// Ensure at least 50ms between requests
+		if wait := 50*time.Millisecond - time.Since(lastRequestTime); wait > 0 {
+			select {
+			case <-ctx.Done():
+				return game, ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+		state, err := client.State(ctx, game.GameID)
+		lastRequestTime = time.Now()
+		if err != nil {
+			return game, err
+		}
+		game = state
+		if game.GameStatus != "ONGOING" || game.Turn != game.Color {
+			continue
+		}
+
+		bestMove := think(game)
+		fmt.Printf("Best move: %v\n", bestMove)
+		after, err := client.Move(ctx, game.GameID, bestMove)
+		if errors.Is(err, ErrInvalidMove) {
+			fmt.Println("Move failed, trying another position...")
+			bestMove = fallbackMove(convertGameboard(game.Gameboard))
+			after, err = client.Move(ctx, game.GameID, bestMove)
+		}
+		lastRequestTime = time.Now()
+		switch {
+		case err == nil:
+			fmt.Printf("Move %v recorded successfully\n", bestMove)
+			game = after
+		case errors.Is(err, ErrInvalidMove), errors.Is(err, ErrNotYourTurn), errors.Is(err, ErrGameOver):
+			// the next poll shows what the server thinks
+			fmt.Println("Move failed:", err)
+		default:
+			return game, err
+		}
 	}
-	fmt.Println("Game initialized successfully!")
+	return game, nil
+}
 
-	// create a while loop to keep the game running
-	for {
-		if newGame.GameStatus != "ONGOING" {
-			fmt.Println("Game is finished with status:", newGame.GameStatus)
-			// Start a new game when the current game completes
-			if newGame.GameStatus == "LEAVE" {
-				fmt.Println("Exiting game...")
-				return
-			} else if newGame.GameStatus == "BLACKWON" || newGame.GameStatus == "WHITEWON" || newGame.GameStatus == "DRAW" {
-				fmt.Println("Starting new game...")
-				startNewGame()
-				return
+// fallbackMove is the empty cell closest to the middle of the board, next to a stone if there is one,
+// for when the engine's move was refused
+func fallbackMove(board [][]int) Point {
+	size := len(board)
+	best, bestScore := noPoint, math.MaxInt32
+	for row := 0; row < size; row++ {
+		for col := 0; col < size; col++ {
+			p := Point{Row: row, Col: col}
+			if board[row][col] != EMPTY {
+				continue
 			}
-			break
-		} else {
-			// Ensure at least 50ms between requests
-			timeSinceLastRequest := time.Since(lastRequestTime)
-			if timeSinceLastRequest < 50*time.Millisecond {
-				time.Sleep(50*time.Millisecond - timeSinceLastRequest)
+			score := abs(row-size/2) + abs(col-size/2)
+			if !hasNeighbor(board, p) {
+				score += 2 * size
 			}
-		
-			// Construct the endpoint URL
-			endpoint := fmt.Sprintf("%s/%s/%d", url, student_id, newGame.GameID)
-		
-			// Send the HTTP request
-			response, err := http.Get(endpoint)
-			if err != nil {
-				fmt.Printf("Error making request: %v", err)
-				return
-			}
-			defer response.Body.Close()
-		
-			// Record the time of the last request
-			lastRequestTime = time.Now()
-			// Update the game state with the new data
-			body, err := io.ReadAll(response.Body)
-			if err != nil {
-				fmt.Println("Error reading response:", err)
-				return
-			}
-
-			err = json.Unmarshal(body, &newGame)
-			if err != nil {
-				fmt.Println("Error unmarshalling response:", err)
-				return
-			}
-
-			// Print board with proper indexing for better visualization
-			board := convertGameboard(newGame.Gameboard)
-			printBoardWithIndexing(board)
-			globalBoard = board
-
-			// Send a move request if it's our turn
-			if newGame.Turn == newGame.Color {
-				bestMove := findBestMove(board, newGame.Color, depth)
-
-				fmt.Printf("Best move: %v\n", bestMove)
-				// Send the move
-				err = newGame.move(bestMove[0], bestMove[1])
-				if err != nil {
-					fmt.Println("Move failed, trying another position...")
-					fmt.Print(board)
-					fmt.Print(newGame.GameID)
-					fmt.Print(newGame.Color)
-				} else {
-					fmt.Printf("Move [%d,%d] recorded successfully\n", bestMove[0], bestMove[1])
-				}
+			if score < bestScore {
+				best, bestScore = p, score
 			}
 		}
 	}
+	return best
 }
 
-// Helper function to convert the gameboard from the API format to the algorithm format
-// Ensuring proper 0,0 indexing
+// Helper function to convert the gameboard from the API format to the algorithm format.
+// Both are [row][col], so it is a copy the engine can change.
 func convertGameboard(gameboard [][]int) [][]int {
 	board := make([][]int, len(gameboard))
 	for i := range gameboard {
@@ -215,7 +208,7 @@ func convertGameboard(gameboard [][]int) [][]int {
 
 // Print the board with proper indexing for debugging
 func printBoardWithIndexing(board [][]int) {
-	fmt.Println("Board with indexing (x,y):")
+	fmt.Println("Board with indexing (rows down, columns across):")
 	fmt.Print("  ")
 	for j := 0; j < len(board[0]); j++ {
 		fmt.Printf("%2d ", j)
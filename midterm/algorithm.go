@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"math"
+	"time"
 )
 
 const (
@@ -30,12 +31,42 @@ var DIRECTIONS = [][]int{
 }
 
 // For initial move on an empty board, use the center position
-func firstMove(board [][]int) [2]int {
+func firstMove(board [][]int) Point {
 	size := len(board)
-	return [2]int{size / 2, size / 2} // Center of board
+	return Point{Row: size / 2, Col: size / 2} // Center of board
 }
 
-func findBestMove(board [][]int, color string, maxDepth int) [2]int {
+// alphaBetaEngine is the minimax search with alpha-beta pruning. The transposition table stays
+// from one move to the next, the rest belongs to the search going on.
+type alphaBetaEngine struct {
+	cfg      searchConfig
+	tt       *transpositionTable // nil searches without one
+	killers  [][2][2]int         // the last two moves that caused a cutoff at each remaining depth
+	history  [][][3]int          // how often playing a cell caused a cutoff for each stone, weighted by depth
+	eval     *patternEval        // follows the position minimax looks at, nil uses evaluate
+	deadline time.Time           // stops the search when set, the zero time searches to the full depth
+	nodes    int                 // minimax calls of the current search
+	stopped  bool                // set once the time ran out
+}
+
+func newAlphaBetaEngine(cfg searchConfig) *alphaBetaEngine {
+	return &alphaBetaEngine{cfg: cfg}
+}
+
+func (e *alphaBetaEngine) name() string {
+	return "alphabeta"
+}
+
+// findBestMove picks the move for color on board[row][col], the same order as the server's gameboard
+func (e *alphaBetaEngine) findBestMove(board [][]int, color string, maxDepth int, deadline time.Time) (Point, error) {
+	if err := checkBoardSize(len(board)); err != nil {
+		return noPoint, err
+	}
+	return e.bestMove(board, color, maxDepth, deadline), nil
+}
+
+func (e *alphaBetaEngine) bestMove(board [][]int, color string, maxDepth int, deadline time.Time) Point {
+	e.deadline = deadline
 	// Check if this is the first move
 	isEmpty := true
 	for i := range board {
@@ -56,9 +87,6 @@ func findBestMove(board [][]int, color string, maxDepth int) [2]int {
 
 	player := toPlayer(color)
 	opponent := getOpponent(player)
-	bestScore := math.MinInt32
-	var bestMove [2]int
-
 	// Generate possible moves
 	moves := generateMoves(board)
 
@@ -67,15 +95,18 @@ func findBestMove(board [][]int, color string, maxDepth int) [2]int {
 		return firstMove(board)
 	}
 
+	pos := newPosition(board)
+
 	// Check for immediate winning move
 	for _, move := range moves {
 		row, col := move[0], move[1]
-		newBoard := makeCopy(board)
-		newBoard[row][col] = player
-		if hasWon(newBoard, player) {
-			printBoardClearly(newBoard)
+		pos.play(row, col, player)
+		won := pos.hasFive(player)
+		pos.undo(row, col)
+		if won {
+			printBoardClearly(board)
 			fmt.Println("Found winning move:", row, col)
-			return [2]int{row, col}
+			return Point{Row: row, Col: col}
 		}
 	}
 
@@ -86,9 +117,10 @@ func findBestMove(board [][]int, color string, maxDepth int) [2]int {
 
 	for _, move := range moves {
 		row, col := move[0], move[1]
-		newBoard := makeCopy(board)
-		newBoard[row][col] = opponent
-		if hasWon(newBoard, opponent) {
+		pos.play(row, col, opponent)
+		won := pos.hasFive(opponent)
+		pos.undo(row, col)
+		if won {
 			blockingMoves = append(blockingMoves, [2]int{row, col})
 			// Calculate the "centrality" of this move in the winning line
 			// This helps choose the most effective blocking position
@@ -112,69 +144,55 @@ func findBestMove(board [][]int, color string, maxDepth int) [2]int {
 		printBoardClearly(board)
 
 		fmt.Println("Blocking opponent's winning move:", bestMove[0], bestMove[1])
-		// revert to the original best move
-		tempMove := bestMove[0]
-		bestMove[0] = bestMove[1]
-		bestMove[1] = tempMove
+		return pointOf(bestMove)
+	}
 
-		return bestMove
+	// Look for a forced win by threats before searching, and for one of the opponent to stop
+	if e.cfg.useThreats {
+		if line, kind, nodes := e.threatWin(board, player, e.cfg.threatNodes); kind != "" {
+			fmt.Printf("Found %s in %d nodes: %v\n", kind, nodes, line)
+			return pointOf(line[0])
+		}
+		if line, kind, _ := e.threatWin(board, opponent, e.cfg.threatNodes); kind != "" {
+			fmt.Printf("Opponent has a %s: %v\n", kind, line)
+			if move, ok := e.defendThreats(board, player, line); ok {
+				fmt.Println("Defending against it with", move)
+				return pointOf(move)
+			}
+			fmt.Println("No defence found, searching anyway")
+		}
 	}
 
-	// Use minimax with alpha-beta pruning for other moves
-	for _, move := range moves {
-		row, col := move[0], move[1]
-		newBoard := makeCopy(board)
-		newBoard[row][col] = player
-		score := minimax(newBoard, maxDepth, math.MinInt32, math.MaxInt32, false, player, opponent)
+	if e.cfg.useTT && e.tt == nil {
+		e.tt = newTranspositionTable(e.cfg.ttBits)
+	}
+	if e.tt != nil {
+		e.tt.newSearch()
+	}
+	e.resetOrdering(len(board), maxDepth)
 
-		if score > bestScore {
-			bestScore = score
-			bestMove = [2]int{row, col}
-		}
+	// With a time budget search deeper and deeper until it runs out
+	if !e.deadline.IsZero() {
+		return pointOf(e.iterativeDeepening(pos, moves, maxDepth, player, opponent))
 	}
 
-	return bestMove
+	// Use minimax with alpha-beta pruning for other moves
+	e.nodes, e.stopped = 0, false
+	bestMove, _, _ := e.searchRoot(pos, moves, maxDepth, player, opponent)
+	return pointOf(bestMove)
 }
 
-var once = true
-var twice = true
-
+// generateMoves is every empty cell up to two cells from a stone, as {row, col}, or every empty cell
+// when there are no stones
 func generateMoves(board [][]int) [][2]int {
 	size := len(board)
 	moves := make([][2]int, 0)
 	visited := make([][]bool, size)
-	visitedCoords := make([][2]int, 0)
-	correct_board := give_correct_board()
 
 	for i := range visited {
 		visited[i] = make([]bool, size)
 	}
 
-	// Reset visited array
-	for x := 0; x < size; x++ {
-		for y := 0; y < size; y++ {
-			visited[x][y] = false
-		}
-	}
-
-	// Gather visitedCoords using correct indexing
-	for x := 0; x < size; x++ {
-		for y := 0; y < size; y++ {
-			if correct_board[y][x] != EMPTY {
-				visited[x][y] = true
-				visitedCoords = append(visitedCoords, [2]int{x, y})
-			}
-		}
-	}
-
-	if len(visitedCoords) > 10 && once {
-		fmt.Println("Correct Board: qerqwrqewrewq ")
-		printBoardWithIndexing(correct_board)
-		once = false
-		fmt.Println("Visited Coords:")
-		fmt.Println(visitedCoords)
-	}
-
 	// Generate moves around existing pieces
 	for x := 0; x < size; x++ {
 		for y := 0; y < size; y++ {
@@ -197,8 +215,8 @@ func generateMoves(board [][]int) [][2]int {
 	if len(moves) == 0 {
 		for x := 0; x < size; x++ {
 			for y := 0; y < size; y++ {
-				if board[x][y] == EMPTY && !visited[x][y] {
-					moves = append(moves, [2]int{y, x})
+				if board[x][y] == EMPTY {
+					moves = append(moves, [2]int{x, y})
 				}
 			}
 		}
@@ -207,60 +225,138 @@ func generateMoves(board [][]int) [][2]int {
 	return moves
 }
 
-func minimax(board [][]int, depth int, alpha int, beta int, maximizingPlayer bool, player int, opponent int) int {
+// minimax looks the position up in the transposition table before searching it
+func (e *alphaBetaEngine) minimax(pos *position, depth int, alpha int, beta int, maximizingPlayer bool, player int, opponent int) int {
+	if e.outOfTime() {
+		return 0
+	}
+
+	key := positionKey(pos.hash, maximizingPlayer, player)
+	ttMove := [2]int{-1, -1}
+	if e.tt != nil {
+		if entry, ok := e.tt.probe(key); ok {
+			ttMove, _ = entry.bestMove()
+			if int(entry.depth) >= depth {
+				score := int(entry.score)
+				switch entry.bound {
+				case ttExact:
+					e.tt.cutoffs++
+					return score
+				case ttLower:
+					alpha = max(alpha, score)
+				case ttUpper:
+					beta = min(beta, score)
+				}
+				if beta <= alpha {
+					e.tt.cutoffs++
+					return score
+				}
+			}
+		}
+	}
+
+	score, best := e.minimaxSearch(pos, depth, alpha, beta, maximizingPlayer, player, opponent, ttMove)
+	if e.tt != nil && !e.stopped {
+		bound := ttExact
+		if score <= alpha {
+			bound = ttUpper
+		} else if score >= beta {
+			bound = ttLower
+		}
+		e.tt.store(key, depth, score, bound, best)
+	}
+	return score
+}
+
+// minimaxSearch is the alpha-beta search itself, it tries ttMove first and returns the score with the best move
+func (e *alphaBetaEngine) minimaxSearch(pos *position, depth int, alpha int, beta int, maximizingPlayer bool, player int, opponent int, ttMove [2]int) (int, [2]int) {
+	noMove := [2]int{-1, -1}
 	// Terminal conditions
-	if hasWon(board, player) {
-		return FIVE_IN_A_ROW
+	if pos.hasFive(player) {
+		return FIVE_IN_A_ROW, noMove
 	}
-	if hasWon(board, opponent) {
-		return -FIVE_IN_A_ROW
+	if pos.hasFive(opponent) {
+		return -FIVE_IN_A_ROW, noMove
 	}
-	if isBoardFull(board) || depth == 0 {
-		return evaluate(board, player, opponent)
+	if pos.full() || depth == 0 {
+		if e.eval != nil {
+			return e.eval.score(player, opponent), noMove
+		}
+		return evaluate(pos.toBoard(), player, opponent), noMove
 	}
 
-	moves := generateMoves(board)
+	moves := pos.moves()
+	stone := opponent
+	if maximizingPlayer {
+		stone = player
+	}
+	if e.cfg.useOrdering {
+		moves = e.orderMoves(pos, moves, depth, stone, ttMove, true)
+	} else if ttMove != noMove {
+		moves = moveFirst(moves, ttMove)
+	}
+	best := noMove
 
 	if maximizingPlayer {
 		maxVal := math.MinInt32
 		for _, move := range moves {
 			x, y := move[0], move[1]
-			if board[x][y] != EMPTY {
+			if pos.at(x, y) != EMPTY {
 				continue
 			}
 
-			newBoard := makeCopy(board)
-			newBoard[x][y] = player
-
-			val := minimax(newBoard, depth-1, alpha, beta, false, player, opponent)
-			maxVal = max(maxVal, val)
+			e.playMove(pos, x, y, player)
+			val := e.minimax(pos, depth-1, alpha, beta, false, player, opponent)
+			e.undoMove(pos, x, y)
+			if val > maxVal {
+				maxVal, best = val, move
+			}
 			alpha = max(alpha, val)
 
 			if beta <= alpha {
+				e.recordCutoff(move, depth, stone)
 				break // Beta cutoff
 			}
 		}
-		return maxVal
+		return maxVal, best
 	} else {
 		minVal := math.MaxInt32
 		for _, move := range moves {
 			x, y := move[0], move[1]
-			if board[x][y] != EMPTY {
+			if pos.at(x, y) != EMPTY {
 				continue
 			}
 
-			newBoard := makeCopy(board)
-			newBoard[x][y] = opponent
-
-			val := minimax(newBoard, depth-1, alpha, beta, true, player, opponent)
-			minVal = min(minVal, val)
+			e.playMove(pos, x, y, opponent)
+			val := e.minimax(pos, depth-1, alpha, beta, true, player, opponent)
+			e.undoMove(pos, x, y)
+			if val < minVal {
+				minVal, best = val, move
+			}
 			beta = min(beta, val)
 
 			if beta <= alpha {
+				e.recordCutoff(move, depth, stone)
 				break // Alpha cutoff
 			}
 		}
-		return minVal
+		return minVal, best
+	}
+}
+
+// playMove makes the move on the position and keeps the evaluation in step
+func (e *alphaBetaEngine) playMove(pos *position, x, y, stone int) {
+	pos.play(x, y, stone)
+	if e.eval != nil {
+		e.eval.update(x, y)
+	}
+}
+
+// undoMove takes the move back
+func (e *alphaBetaEngine) undoMove(pos *position, x, y int) {
+	pos.undo(x, y)
+	if e.eval != nil {
+		e.eval.update(x, y)
 	}
 }
 
@@ -0,0 +1,392 @@
+package main
+
+import (
+	"fmt"
+	"math/bits"
+	"time"
+)
+
+// bitboard has a bit for every cell, cell x, y is bit x*size+y. Six words are enough for 19x19.
+type bitboard [6]uint64
+
+// maxBoardSize is the largest board whose cells fit in a bitboard
+const maxBoardSize = 19
+
+// checkBoardSize tells whether the engines can play on a size x size board
+func checkBoardSize(size int) error {
+	if size*size > len(bitboard{})*64 {
+		return fmt.Errorf("a %dx%d board does not fit in a bitboard, the largest is %dx%d", size, size, maxBoardSize, maxBoardSize)
+	}
+	return nil
+}
+
+func (b *bitboard) set(i int) {
+	b[i>>6] |= 1 << (i & 63)
+}
+
+func (b *bitboard) clear(i int) {
+	b[i>>6] &^= 1 << (i & 63)
+}
+
+func (b *bitboard) has(i int) bool {
+	return b[i>>6]&(1<<(i&63)) != 0
+}
+
+// shiftUp moves every bit n cells to a higher index, n is less than 64
+func (b bitboard) shiftUp(n int) bitboard {
+	var out bitboard
+	for w := len(b) - 1; w >= 0; w-- {
+		out[w] = b[w] << n
+		if w > 0 {
+			out[w] |= b[w-1] >> (64 - n)
+		}
+	}
+	return out
+}
+
+// shiftDown moves every bit n cells to a lower index, n is less than 64
+func (b bitboard) shiftDown(n int) bitboard {
+	var out bitboard
+	for w := range b {
+		out[w] = b[w] >> n
+		if w < len(b)-1 {
+			out[w] |= b[w+1] << (64 - n)
+		}
+	}
+	return out
+}
+
+func (b bitboard) or(o bitboard) bitboard {
+	for w := range b {
+		b[w] |= o[w]
+	}
+	return b
+}
+
+func (b bitboard) and(o bitboard) bitboard {
+	for w := range b {
+		b[w] &= o[w]
+	}
+	return b
+}
+
+func (b bitboard) andNot(o bitboard) bitboard {
+	for w := range b {
+		b[w] &^= o[w]
+	}
+	return b
+}
+
+func (b bitboard) count() int {
+	n := 0
+	for _, word := range b {
+		n += bits.OnesCount64(word)
+	}
+	return n
+}
+
+// position is a board for the search. Moves are made and taken back in place instead of copying
+// the board, and the hash and the five in a rows are kept up to date as they are.
+type position struct {
+	size   int
+	stones [3]bitboard // the stones of BLACK and WHITE, EMPTY is unused
+	count  int         // stones on the board
+	hash   uint64      // Zobrist hash of the stones
+	fives  [3]int      // moves that made five in a row, for each player
+
+	// masks for spreading stones to their neighbours without wrapping around an edge
+	cells     bitboard    // every cell of the board
+	fromCol   [3]bitboard // cells with y >= k
+	beforeCol [3]bitboard // cells with y < size-k
+}
+
+// newPosition makes a position from a board[x][y], the board has to pass checkBoardSize
+func newPosition(board [][]int) *position {
+	size := len(board)
+	if err := checkBoardSize(size); err != nil {
+		panic(err)
+	}
+	p := &position{size: size, hash: hashBoard(board)}
+	for x := 0; x < size; x++ {
+		for y := 0; y < size; y++ {
+			i := x*size + y
+			p.cells.set(i)
+			for k := 1; k <= 2; k++ {
+				if y >= k {
+					p.fromCol[k].set(i)
+				}
+				if y < size-k {
+					p.beforeCol[k].set(i)
+				}
+			}
+			if stone := board[x][y]; stone != EMPTY {
+				p.stones[stone].set(i)
+				p.count++
+			}
+		}
+	}
+	for player := BLACK; player <= WHITE; player++ {
+		if hasWon(board, player) {
+			p.fives[player] = 1
+		}
+	}
+	return p
+}
+
+func (p *position) at(x, y int) int {
+	i := x*p.size + y
+	switch {
+	case p.stones[BLACK].has(i):
+		return BLACK
+	case p.stones[WHITE].has(i):
+		return WHITE
+	}
+	return EMPTY
+}
+
+func (p *position) inside(x, y int) bool {
+	return x >= 0 && x < p.size && y >= 0 && y < p.size
+}
+
+// play puts the stone on x, y, which has to be empty
+func (p *position) play(x, y, stone int) {
+	p.stones[stone].set(x*p.size + y)
+	p.count++
+	p.hash ^= stoneKey(x, y, stone)
+	if p.fiveAt(x, y, stone) {
+		p.fives[stone]++
+	}
+}
+
+// undo takes the stone on x, y back off the board
+func (p *position) undo(x, y int) {
+	stone := p.at(x, y)
+	if stone == EMPTY {
+		return
+	}
+	if p.fiveAt(x, y, stone) {
+		p.fives[stone]--
+	}
+	p.stones[stone].clear(x*p.size + y)
+	p.count--
+	p.hash ^= stoneKey(x, y, stone)
+}
+
+// fiveAt tells if the stone on x, y is part of five in a row, only the lines through it are looked at
+func (p *position) fiveAt(x, y, stone int) bool {
+	own := &p.stones[stone]
+	for _, dir := range DIRECTIONS {
+		dx, dy := dir[0], dir[1]
+		count := 1
+		for nx, ny := x+dx, y+dy; count < 5 && p.inside(nx, ny) && own.has(nx*p.size+ny); nx, ny = nx+dx, ny+dy {
+			count++
+		}
+		for nx, ny := x-dx, y-dy; count < 5 && p.inside(nx, ny) && own.has(nx*p.size+ny); nx, ny = nx-dx, ny-dy {
+			count++
+		}
+		if count >= 5 {
+			return true
+		}
+	}
+	return false
+}
+
+// wouldFive tells if the stone played on the empty x, y makes five in a row
+func (p *position) wouldFive(x, y, stone int) bool {
+	i := x*p.size + y
+	p.stones[stone].set(i)
+	five := p.fiveAt(x, y, stone)
+	p.stones[stone].clear(i)
+	return five
+}
+
+// fiveCellThrough is an empty cell on the lines through x, y where the stone makes five in a row
+func (p *position) fiveCellThrough(x, y, stone int) ([2]int, bool) {
+	for _, dir := range DIRECTIONS {
+		for k := -4; k <= 4; k++ {
+			nx, ny := x+k*dir[0], y+k*dir[1]
+			if k != 0 && p.inside(nx, ny) && p.at(nx, ny) == EMPTY && p.wouldFive(nx, ny, stone) {
+				return [2]int{nx, ny}, true
+			}
+		}
+	}
+	return [2]int{}, false
+}
+
+func (p *position) hasFive(player int) bool {
+	return p.fives[player] > 0
+}
+
+func (p *position) full() bool {
+	return p.count == p.size*p.size
+}
+
+// line reads the stones from x, y in direction d up to the edge of the board into buffer
+func (p *position) line(d, x, y int, buffer []int8) []int8 {
+	dx, dy := DIRECTIONS[d][0], DIRECTIONS[d][1]
+	line := buffer[:0]
+	for ; p.inside(x, y); x, y = x+dx, y+dy {
+		line = append(line, int8(p.at(x, y)))
+	}
+	return line
+}
+
+// near is every empty cell at most two cells from a stone, the same cells generateMoves looks at
+func (p *position) near() bitboard {
+	occupied := p.stones[BLACK].or(p.stones[WHITE])
+	rows := occupied
+	for k := 1; k <= 2; k++ {
+		rows = rows.or(occupied.shiftUp(k).and(p.fromCol[k]))
+		rows = rows.or(occupied.shiftDown(k).and(p.beforeCol[k]))
+	}
+	square := rows
+	for k := 1; k <= 2; k++ {
+		square = square.or(rows.shiftUp(k * p.size))
+		square = square.or(rows.shiftDown(k * p.size))
+	}
+	return square.and(p.cells).andNot(occupied)
+}
+
+// moves are the cells of near in board order
+func (p *position) moves() [][2]int {
+	near := p.near()
+	moves := make([][2]int, 0, near.count())
+	for w, word := range near {
+		for word != 0 {
+			i := w*64 + bits.TrailingZeros64(word)
+			moves = append(moves, [2]int{i / p.size, i % p.size})
+			word &= word - 1
+		}
+	}
+	return moves
+}
+
+// toBoard is the position as a board[x][y]
+func (p *position) toBoard() [][]int {
+	board := make([][]int, p.size)
+	for x := range board {
+		board[x] = make([]int, p.size)
+		for y := range board[x] {
+			board[x][y] = p.at(x, y)
+		}
+	}
+	return board
+}
+
+// sliceMoves are the empty cells at most two cells from a stone in board order, like position.moves
+// but on a slice board. It is only here to compare against.
+func sliceMoves(board [][]int) [][2]int {
+	size := len(board)
+	var moves [][2]int
+	for x := 0; x < size; x++ {
+		for y := 0; y < size; y++ {
+			if board[x][y] != EMPTY {
+				continue
+			}
+		near:
+			for nx := max(x-2, 0); nx <= min(x+2, size-1); nx++ {
+				for ny := max(y-2, 0); ny <= min(y+2, size-1); ny++ {
+					if board[nx][ny] != EMPTY {
+						moves = append(moves, [2]int{x, y})
+						break near
+					}
+				}
+			}
+		}
+	}
+	return moves
+}
+
+// walkSlice visits every sequence of depth moves like the search did before the bitboards, copying
+// the board for every move and looking for five in a row over the whole board
+func walkSlice(board [][]int, depth, stone int) int {
+	nodes := 1
+	if depth == 0 || hasWon(board, BLACK) || hasWon(board, WHITE) {
+		return nodes
+	}
+	for _, move := range sliceMoves(board) {
+		newBoard := makeCopy(board)
+		newBoard[move[0]][move[1]] = stone
+		nodes += walkSlice(newBoard, depth-1, getOpponent(stone))
+	}
+	return nodes
+}
+
+// walkPosition visits the same sequences making and taking back the moves on the position
+func walkPosition(p *position, depth, stone int) int {
+	nodes := 1
+	if depth == 0 || p.hasFive(BLACK) || p.hasFive(WHITE) {
+		return nodes
+	}
+	for _, move := range p.moves() {
+		p.play(move[0], move[1], stone)
+		nodes += walkPosition(p, depth-1, getOpponent(stone))
+		p.undo(move[0], move[1])
+	}
+	return nodes
+}
+
+// runBenchBoard walks the bench positions to the depth on slices and on bitboards, which have to
+// visit the same number of nodes, then runs the real search to compare nodes per second
+func runBenchBoard(cfg searchConfig, size int) {
+	walkDepth := cfg.depth
+	fmt.Printf("Every sequence of %d moves, nodes per second on slices and bitboards\n", walkDepth)
+	fmt.Printf("pos %10s %14s %14s %8s\n", "nodes", "slice", "bitboard", "speedup")
+	var sliceNodes, bitNodes int
+	var sliceTime, bitTime time.Duration
+	failed := false
+	for i, board := range benchPositions(10, size) {
+		player := BLACK
+		if countStones(board, BLACK) > countStones(board, WHITE) {
+			player = WHITE
+		}
+		start := time.Now()
+		slice := walkSlice(board, walkDepth, player)
+		sliceElapsed := time.Since(start)
+		start = time.Now()
+		bit := walkPosition(newPosition(board), walkDepth, player)
+		bitElapsed := time.Since(start)
+
+		sliceNodes += slice
+		bitNodes += bit
+		sliceTime += sliceElapsed
+		bitTime += bitElapsed
+		mark := ""
+		if slice != bit {
+			failed = true
+			mark = fmt.Sprintf("  MISMATCH, bitboards visited %d", bit)
+		}
+		fmt.Printf("%3d %10d %14.0f %14.0f %7.1fx%s\n", i+1, slice, perSecond(slice, sliceElapsed), perSecond(bit, bitElapsed),
+			sliceElapsed.Seconds()/bitElapsed.Seconds(), mark)
+	}
+	fmt.Printf("all %10d %14.0f %14.0f %7.1fx\n", sliceNodes, perSecond(sliceNodes, sliceTime), perSecond(bitNodes, bitTime),
+		sliceTime.Seconds()/bitTime.Seconds())
+
+	// the search itself, with the table and the ordering as set on the command line
+	searchNodes := 0
+	start := time.Now()
+	for _, board := range benchPositions(10, size) {
+		player := BLACK
+		if countStones(board, BLACK) > countStones(board, WHITE) {
+			player = WHITE
+		}
+		e := newAlphaBetaEngine(cfg)
+		if cfg.useTT {
+			e.tt = newTranspositionTable(cfg.ttBits)
+		}
+		e.resetOrdering(len(board), walkDepth)
+		pos := newPosition(board)
+		e.searchRoot(pos, pos.moves(), walkDepth, player, getOpponent(player))
+		searchNodes += e.nodes
+	}
+	elapsed := time.Since(start)
+	fmt.Printf("Search to depth %d: %d nodes in %v, %.0f nodes per second\n", walkDepth, searchNodes,
+		elapsed.Round(time.Millisecond), perSecond(searchNodes, elapsed))
+	if failed {
+		fmt.Println("The slice and bitboard walks visited different nodes")
+	}
+}
+
+func perSecond(nodes int, elapsed time.Duration) float64 {
+	return float64(nodes) / elapsed.Seconds()
+}
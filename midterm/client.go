@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Errors the server can answer a request with, check them with errors.Is
+var (
+	ErrInvalidMove = errors.New("invalid move")
+	ErrNotYourTurn = errors.New("not your turn")
+	ErrGameOver    = errors.New("game is over")
+	ErrUnknownGame = errors.New("unknown game")
+)
+
+// Client talks to the game server. Every call returns the game as the server sees it after the request.
+type Client interface {
+	Start(ctx context.Context) (Game, error)
+	State(ctx context.Context, gameID int) (Game, error)
+	Move(ctx context.Context, gameID int, p Point) (Game, error)
+}
+
+// serverError is a request the server turned down
+type serverError struct {
+	code   int
+	status string // request_status, or the body when it was not JSON
+	kind   error
+}
+
+func (e *serverError) Error() string {
+	return fmt.Sprintf("server answered %d %s: %s", e.code, http.StatusText(e.code), e.status)
+}
+
+func (e *serverError) Unwrap() error {
+	return e.kind
+}
+
+// temporary is true for answers worth asking again, the server being overloaded or down for a moment
+func (e *serverError) temporary() bool {
+	return e.code >= 500 || e.code == http.StatusTooManyRequests
+}
+
+// connectionError is a request that got no complete answer. It may still have reached the server.
+type connectionError struct {
+	err error
+}
+
+func (e *connectionError) Error() string {
+	return e.err.Error()
+}
+
+func (e *connectionError) Unwrap() error {
+	return e.err
+}
+
+// retryable is true for the failures worth trying again: no complete answer, a 5xx or a 429.
+// Anything else, like a 200 the game can't be read from, would only come back the same.
+func retryable(err error) bool {
+	var refused *serverError
+	if errors.As(err, &refused) {
+		return refused.temporary()
+	}
+	var dropped *connectionError
+	return errors.As(err, &dropped)
+}
+
+// checkResponse turns a status code and the game the server sent into an error. The request_status
+// decides the kind, and any other 400 is taken as an invalid move like the client always did.
+func checkResponse(code int, game Game, body string) error {
+	if code == http.StatusOK {
+		return nil
+	}
+	status := game.RequestStatus
+	if status == "" {
+		status = strings.TrimSpace(body)
+	}
+	lower := strings.ToLower(status)
+	err := &serverError{code: code, status: status}
+	switch {
+	case strings.Contains(lower, "turn"):
+		err.kind = ErrNotYourTurn
+	case strings.Contains(lower, "over") || strings.Contains(lower, "finished"):
+		err.kind = ErrGameOver
+	case code == http.StatusNotFound:
+		err.kind = ErrUnknownGame
+	case code == http.StatusBadRequest || strings.Contains(lower, "invalid"):
+		err.kind = ErrInvalidMove
+	}
+	return err
+}
+
+// httpClient is the Client for the real server, retrying requests that failed for a passing reason
+type httpClient struct {
+	baseURL   string
+	studentID string
+	http      *http.Client
+	retries   int
+	backoff   time.Duration
+}
+
+func newHTTPClient(baseURL, studentID string, timeout time.Duration, retries int, backoff time.Duration) *httpClient {
+	return &httpClient{
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		studentID: studentID,
+		http:      &http.Client{Timeout: timeout},
+		retries:   retries,
+		backoff:   backoff,
+	}
+}
+
+func (c *httpClient) Start(ctx context.Context) (Game, error) {
+	return c.get(ctx, fmt.Sprintf("/%s/start", c.studentID))
+}
+
+func (c *httpClient) State(ctx context.Context, gameID int) (Game, error) {
+	return c.get(ctx, fmt.Sprintf("/%s/%d", c.studentID, gameID))
+}
+
+// Move sends a move. When an earlier try may have reached the server before failing, the retry
+// can be refused because the stone is already there, so the board decides if the move was made.
+func (c *httpClient) Move(ctx context.Context, gameID int, p Point) (Game, error) {
+	x, y := p.URL()
+	path := fmt.Sprintf("/%s/%d/%d/%d", c.studentID, gameID, x, y)
+	game, retried, err := c.getWithRetries(ctx, path)
+	if retried && (errors.Is(err, ErrInvalidMove) || errors.Is(err, ErrNotYourTurn)) {
+		state, stateErr := c.State(ctx, gameID)
+		if stateErr == nil && gameboardAt(state.Gameboard, p) == toPlayer(state.Color) {
+			fmt.Printf("Move %v was already made before the retry\n", p)
+			return state, nil
+		}
+	}
+	return game, err
+}
+
+func (c *httpClient) get(ctx context.Context, path string) (Game, error) {
+	game, _, err := c.getWithRetries(ctx, path)
+	return game, err
+}
+
+// getWithRetries sends the request until it gets a real answer, waiting twice as long after every
+// failure. It also tells whether any try failed, since that try might still have reached the server.
+func (c *httpClient) getWithRetries(ctx context.Context, path string) (Game, bool, error) {
+	wait := c.backoff
+	retried := false
+	for attempt := 0; ; attempt++ {
+		game, err := c.fetch(ctx, path)
+		if ctx.Err() != nil || !retryable(err) || attempt >= c.retries {
+			return game, retried, err
+		}
+		retried = true
+		// a little jitter so retries from a hiccup don't all line up
+		sleep := wait + time.Duration(rand.Int63n(int64(wait)/4+1))
+		fmt.Printf("Request %s failed (%v), retrying in %v\n", path, err, sleep.Round(time.Millisecond))
+		select {
+		case <-ctx.Done():
+			return game, retried, ctx.Err()
+		case <-time.After(sleep):
+		}
+		wait *= 2
+	}
+}
+
+// fetch sends one GET and reads the game from the answer
+func (c *httpClient) fetch(ctx context.Context, path string) (Game, error) {
+	var game Game
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return game, err
+	}
+	response, err := c.http.Do(request)
+	if err != nil {
+		return game, &connectionError{err}
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return game, &connectionError{err}
+	}
+	jsonErr := json.Unmarshal(body, &game)
+	if err := checkResponse(response.StatusCode, game, string(body)); err != nil {
+		return game, err
+	}
+	if jsonErr != nil {
+		return game, fmt.Errorf("reading the game from %q: %w", body, jsonErr)
+	}
+	return game, nil
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeClient plays on a gameServer in the same process without any HTTP. The next failures calls
+// fail before reaching the server, which is how a game loop can be tried against a bad connection.
+type fakeClient struct {
+	server   *gameServer
+	failures int
+}
+
+var errFakeConnection = errors.New("fake connection dropped")
+
+func (c *fakeClient) call(do func() (int, Game)) (Game, error) {
+	if c.failures > 0 {
+		c.failures--
+		return Game{}, errFakeConnection
+	}
+	code, game := do()
+	return game, checkResponse(code, game, "")
+}
+
+func (c *fakeClient) Start(ctx context.Context) (Game, error) {
+	return c.call(c.server.start)
+}
+
+func (c *fakeClient) State(ctx context.Context, gameID int) (Game, error) {
+	return c.call(func() (int, Game) { return c.server.state(gameID) })
+}
+
+func (c *fakeClient) Move(ctx context.Context, gameID int, p Point) (Game, error) {
+	return c.call(func() (int, Game) { return c.server.play(gameID, p) })
+}
+
+// flakyHandler is a bad connection in front of a handler. Every failEvery-th request gets a 503 and
+// the one after it is handled but answered only after delay, so the client gives up on it first.
+type flakyHandler struct {
+	next      http.Handler
+	failEvery int
+	delay     time.Duration
+	mu        sync.Mutex
+	requests  int
+}
+
+func (h *flakyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	h.requests++
+	n := h.requests
+	h.mu.Unlock()
+	switch n % h.failEvery {
+	case 0:
+		http.Error(w, "try again later", http.StatusServiceUnavailable)
+	case 1:
+		h.next.ServeHTTP(w, r)
+		if n > 1 {
+			time.Sleep(h.delay)
+		}
+	default:
+		h.next.ServeHTTP(w, r)
+	}
+}
+
+// testServerConfig is the local server with a random opponent and us playing black
+func testServerConfig(games int) serverConfig {
+	cfg := defaultConfig().server
+	cfg.opponent, cfg.color, cfg.games = "random", "black", games
+	return cfg
+}
+
+func simpleMove(game Game) Point {
+	return fallbackMove(convertGameboard(game.Gameboard))
+}
+
+func TestServerErrors(t *testing.T) {
+	ctx := context.Background()
+	cfg := testServerConfig(0)
+	server := newGameServer(1, cfg)
+	fake := &fakeClient{server: server}
+	game, err := fake.Start(ctx)
+	if err != nil || game.GameStatus != "ONGOING" || game.Turn != "black" {
+		t.Fatalf("start: %v, %+v", err, game)
+	}
+	if _, err := fake.Move(ctx, game.GameID, Point{7, 7}); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	waitForReply(t, fake, game.GameID)
+
+	tests := []struct {
+		name string
+		do   func() error
+		want error
+	}{
+		{"move on a taken cell", func() error {
+			_, err := fake.Move(ctx, game.GameID, Point{7, 7})
+			return err
+		}, ErrInvalidMove},
+		{"move off the board", func() error {
+			_, err := fake.Move(ctx, game.GameID, Point{Row: 0, Col: cfg.boardSize})
+			return err
+		}, ErrInvalidMove},
+		{"move out of turn", func() error {
+			server.mu.Lock()
+			server.games[game.GameID].turn = "white"
+			server.mu.Unlock()
+			_, err := fake.Move(ctx, game.GameID, Point{0, 0})
+			return err
+		}, ErrNotYourTurn},
+		{"state of a missing game", func() error {
+			_, err := fake.State(ctx, game.GameID+100)
+			return err
+		}, ErrUnknownGame},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.do(); !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGameLoop(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeClient{server: newGameServer(1, testServerConfig(0))}
+	game, err := fake.Start(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	game, err = playGame(ctx, fake, game, simpleMove)
+	if err != nil || game.GameStatus == "ONGOING" {
+		t.Fatalf("the game loop stopped with %v in a game that is %s", err, game.GameStatus)
+	}
+	if _, err := fake.Move(ctx, game.GameID, Point{0, 0}); !errors.Is(err, ErrGameOver) {
+		t.Errorf("move after the end: got %v, want %v", err, ErrGameOver)
+	}
+	fake.failures = 1
+	if _, err := playGame(ctx, fake, Game{GameID: game.GameID, GameStatus: "ONGOING"}, simpleMove); !errors.Is(err, errFakeConnection) {
+		t.Errorf("dropped connection: got %v, want %v", err, errFakeConnection)
+	}
+}
+
+func TestGamesOverBadConnection(t *testing.T) {
+	ts := httptest.NewServer(&flakyHandler{next: newGameServer(2, testServerConfig(2)), failEvery: 4, delay: 300 * time.Millisecond})
+	defer ts.Close()
+	client := newHTTPClient(ts.URL, student_id, 100*time.Millisecond, 4, 10*time.Millisecond)
+	if err := playGames(context.Background(), client, simpleMove); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// The first move is made on the server but answered too late, so the retry finds the stone already there
+func TestMoveAnsweredTooLateCountsOnce(t *testing.T) {
+	ctx := context.Background()
+	var slowed atomic.Bool
+	server := newGameServer(4, testServerConfig(2))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.ServeHTTP(w, r)
+		if strings.Count(r.URL.Path, "/") == 4 && slowed.CompareAndSwap(false, true) {
+			time.Sleep(300 * time.Millisecond)
+		}
+	}))
+	defer ts.Close()
+	client := newHTTPClient(ts.URL, student_id, 100*time.Millisecond, 2, 10*time.Millisecond)
+	game, err := client.Start(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	game, err = client.Move(ctx, game.GameID, Point{7, 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.Gameboard[7][7] != BLACK {
+		t.Errorf("the move is not on the board the client got back")
+	}
+}
+
+// TestRetries checks that only a missing answer, a 5xx and a 429 are asked again
+func TestRetries(t *testing.T) {
+	const retries = 2
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		requests int32
+		want     error
+	}{
+		{"server stays down", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "try again later", http.StatusServiceUnavailable)
+		}, retries + 1, nil},
+		{"too many requests", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+		}, retries + 1, nil},
+		{"connection dropped", func(w http.ResponseWriter, r *http.Request) {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+		}, retries + 1, nil},
+		{"refused move", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"request_status": "INVALID_MOVE"}`))
+		}, 1, ErrInvalidMove},
+		{"OK with a body that is not a game", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("<html>maintenance</html>"))
+		}, 1, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				tt.handler(w, r)
+			}))
+			defer ts.Close()
+			_, err := newHTTPClient(ts.URL, student_id, time.Second, retries, time.Millisecond).Start(context.Background())
+			if err == nil {
+				t.Fatal("the request did not fail")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+			if n := requests.Load(); n != tt.requests {
+				t.Errorf("the server got %d requests, want %d", n, tt.requests)
+			}
+		})
+	}
+}
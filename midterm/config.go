@@ -0,0 +1,126 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+)
+
+// config is everything the command line sets. main fills it in from the flags and hands each part
+// to the code that uses it, so a test can build its own without touching the flags.
+type config struct {
+	url       string
+	local     bool   // play against a local server instead of url
+	serveAddr string // only run the local server on this address
+	engine    string // engine that picks our moves
+	client    clientConfig
+	server    serverConfig
+	search    searchConfig
+
+	// commands that run instead of playing
+	solvePath     string
+	benchTT       bool
+	benchOrdering bool
+	benchBoard    bool
+	matchGames    int
+	matchMoveTime time.Duration
+}
+
+// clientConfig is how the client talks to the server
+type clientConfig struct {
+	timeout time.Duration
+	retries int
+	backoff time.Duration
+}
+
+// serverConfig is the local server and the opponent it plays
+type serverConfig struct {
+	opponent       string // random, engine or script
+	opponentEngine string
+	script         string
+	color          string // our color, black, white or random
+	boardSize      int
+	clock          float64 // seconds on our clock for a game
+	games          int     // games before the server says LEAVE, 0 for no limit
+	opponentDelay  time.Duration
+	search         searchConfig // for the engine opponent, which searches to search.depth
+}
+
+// searchConfig is how the engines search
+type searchConfig struct {
+	depth          int  // search depth with fixedDepth, and of the benchmarks
+	fixedDepth     bool // always search to depth instead of deepening until the time runs out
+	maxDepth       int  // deepest the time managed search goes
+	safetyMargin   time.Duration
+	maxMoveTime    time.Duration
+	useTT          bool
+	ttBits         int
+	useOrdering    bool
+	topN           int
+	useThreats     bool
+	vcfDepth       int
+	vctDepth       int
+	threatNodes    int
+	eval           string // pattern or classic
+	mctsIterations int
+}
+
+// registerFlags defines the flags on fs, parsing fs fills in cfg
+func registerFlags(fs *flag.FlagSet, cfg *config) {
+	fs.StringVar(&cfg.url, "url", "http://37.27.208.205:55555", "API URL")
+	fs.BoolVar(&cfg.local, "local", false, "Play against a local server started in this process instead of -url")
+	fs.StringVar(&cfg.serveAddr, "serve", "", "Only run the local game server on this address, e.g. :55555")
+	fs.StringVar(&cfg.server.opponent, "opponent", "random", "Opponent on the local server: random, engine (our own engine) or script (moves from -script)")
+	fs.StringVar(&cfg.server.opponentEngine, "opponent-engine", "alphabeta", "Engine the engine opponent uses: alphabeta or mcts")
+	fs.StringVar(&cfg.server.script, "script", "", "File with the scripted opponent's moves, one x,y per line")
+	fs.StringVar(&cfg.server.color, "color", "random", "Color we get on the local server: black, white or random")
+	fs.IntVar(&cfg.server.boardSize, "board-size", 15, "Board size on the local server")
+	fs.Float64Var(&cfg.server.clock, "clock", 300, "Seconds on our clock for a game on the local server")
+	fs.IntVar(&cfg.server.games, "games", 1, "Games the local server plays before telling the client to leave, 0 for no limit")
+	fs.DurationVar(&cfg.server.opponentDelay, "opponent-delay", 0, "How long the local opponent thinks before every move")
+	fs.DurationVar(&cfg.client.timeout, "timeout", 10*time.Second, "How long to wait for the server to answer a request")
+	fs.IntVar(&cfg.client.retries, "retries", 3, "How many times a request that failed for a passing reason is tried again")
+	fs.DurationVar(&cfg.client.backoff, "backoff", 200*time.Millisecond, "Wait before the first retry, doubled for every retry after it")
+	fs.IntVar(&cfg.search.depth, "depth", 2, "Search depth with -fixed-depth")
+	fs.BoolVar(&cfg.search.fixedDepth, "fixed-depth", false, "Always search to -depth instead of deepening until the time for the move runs out")
+	fs.IntVar(&cfg.search.maxDepth, "max-depth", 10, "Deepest the time managed search goes")
+	fs.DurationVar(&cfg.search.safetyMargin, "margin", 3*time.Second, "Time kept on the clock that the search never uses")
+	fs.DurationVar(&cfg.search.maxMoveTime, "move-time", 5*time.Second, "Most time spent on one move, 0 for no limit")
+	fs.BoolVar(&cfg.search.useTT, "tt", true, "Remember searched positions in a transposition table")
+	fs.IntVar(&cfg.search.ttBits, "tt-bits", 20, "The transposition table has 2^tt-bits entries of 24 bytes")
+	fs.BoolVar(&cfg.benchTT, "bench-tt", false, "Search a set of positions to -depth without and with the transposition table, compare and exit")
+	fs.BoolVar(&cfg.search.useOrdering, "ordering", true, "Search the table move, threats, killer moves and moves with a good history first")
+	fs.IntVar(&cfg.search.topN, "top-n", 0, "Only search the best this many moves of every position below the root, 0 searches them all")
+	fs.BoolVar(&cfg.benchOrdering, "bench-ordering", false, "Compare the nodes searched to -depth without and with the table, the ordering and -top-n and exit")
+	fs.BoolVar(&cfg.search.useThreats, "threats", true, "Look for forced wins by fours and threes (VCF and VCT) for both sides before searching")
+	fs.IntVar(&cfg.search.vcfDepth, "vcf-depth", 12, "Most fours in a row the VCF search plays")
+	fs.IntVar(&cfg.search.vctDepth, "vct-depth", 4, "Most threats in a row the VCT search plays")
+	fs.IntVar(&cfg.search.threatNodes, "threat-nodes", 20000, "Nodes the VCF and the VCT search may each use")
+	fs.StringVar(&cfg.solvePath, "solve", "", "Print the forced wins of both sides in the position in this file and exit")
+	fs.StringVar(&cfg.search.eval, "eval", "pattern", "Evaluation: pattern scores line shapes incrementally, classic rescans the board")
+	fs.BoolVar(&cfg.benchBoard, "bench-board", false, "Compare nodes per second on slices and bitboards walking every line of -depth moves, time the search and exit")
+	fs.StringVar(&cfg.engine, "engine", "alphabeta", "Engine that picks our moves: alphabeta or mcts")
+	fs.IntVar(&cfg.search.mctsIterations, "mcts-iterations", 20000, "Iterations of the MCTS engine for a move when it has no time budget, at least one")
+	fs.IntVar(&cfg.matchGames, "match", 0, "Play this many games between the MCTS and the alpha-beta engine offline and exit")
+	fs.DurationVar(&cfg.matchMoveTime, "match-time", time.Second, "Time each engine gets for a move in -match")
+}
+
+// parseConfig reads the command line, the engine opponent searches like our engine
+func parseConfig(fs *flag.FlagSet, args []string) (config, error) {
+	var cfg config
+	registerFlags(fs, &cfg)
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+	if cfg.server.boardSize < 5 || cfg.server.boardSize > maxBoardSize {
+		return cfg, fmt.Errorf("-board-size %d is not between 5 and %d", cfg.server.boardSize, maxBoardSize)
+	}
+	cfg.server.search = cfg.search
+	return cfg, nil
+}
+
+// defaultConfig is the config with every flag left at its default
+func defaultConfig() config {
+	cfg, _ := parseConfig(flag.NewFlagSet("defaults", flag.ContinueOnError), nil)
+	return cfg
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"testing"
+)
+
+func TestParseConfigBoardSize(t *testing.T) {
+	tests := []struct {
+		size string
+		ok   bool
+	}{
+		{"4", false},
+		{"5", true},
+		{"15", true},
+		{"19", true},
+		{"20", false},
+	}
+	for _, tt := range tests {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		cfg, err := parseConfig(fs, []string{"-board-size", tt.size})
+		if tt.ok && (err != nil || cfg.server.boardSize == 0) {
+			t.Errorf("-board-size %s: got %v, want it accepted", tt.size, err)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("-board-size %s was accepted", tt.size)
+		}
+	}
+}
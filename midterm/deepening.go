@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// expectedMoves is about how many moves we make in a game, the clock is shared out over the ones left
+const expectedMoves = 50
+
+// minMovesLeft keeps the budget small when a game goes on longer than expected
+const minMovesLeft = 15
+
+// outOfTime is checked at every node. Looking at the clock is slow, so it is only read every 1024 nodes.
+func (e *alphaBetaEngine) outOfTime() bool {
+	e.nodes++
+	if e.stopped {
+		return true
+	}
+	if e.deadline.IsZero() || e.nodes%1024 != 0 {
+		return false
+	}
+	e.stopped = time.Now().After(e.deadline)
+	return e.stopped
+}
+
+// moveBudget shares what is left on the clock out over the moves the game probably still has,
+// keeping cfg.safetyMargin in reserve and never more than cfg.maxMoveTime for one move
+func moveBudget(timeRemaining float64, moveNumber int, cfg searchConfig) time.Duration {
+	usable := time.Duration(timeRemaining*float64(time.Second)) - cfg.safetyMargin
+	if usable <= 0 {
+		return 0
+	}
+	movesLeft := max(expectedMoves-moveNumber, minMovesLeft)
+	budget := usable / time.Duration(movesLeft)
+	if cfg.maxMoveTime > 0 && budget > cfg.maxMoveTime {
+		budget = cfg.maxMoveTime
+	}
+	return budget
+}
+
+// countStones is how many stones the player has on the board, which is the number of moves it made
+func countStones(board [][]int, player int) int {
+	count := 0
+	for _, row := range board {
+		for _, cell := range row {
+			if cell == player {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// iterativeDeepening searches depth 0, 1, 2, ... until the deadline or maxDepth and returns the
+// best move of the last depth that was searched completely
+func (e *alphaBetaEngine) iterativeDeepening(pos *position, moves [][2]int, maxDepth int, player int, opponent int) [2]int {
+	e.nodes, e.stopped = 0, false
+	if e.cfg.useOrdering {
+		moves = e.orderMoves(pos, moves, maxDepth+1, player, [2]int{-1, -1}, false)
+	}
+	bestMove := moves[0]
+	start := time.Now()
+	for d := 0; d <= maxDepth; d++ {
+		iterationStart := time.Now()
+		move, score, done := e.searchRoot(pos, moveFirst(moves, bestMove), d, player, opponent)
+		if !done {
+			fmt.Printf("Depth %d ran out of time, keeping %v\n", d, bestMove)
+			break
+		}
+		bestMove = move
+		fmt.Printf("Depth %d: best move %v score %d, %d nodes in %v\n", d, bestMove, score, e.nodes, time.Since(start).Round(time.Millisecond))
+		if score >= FIVE_IN_A_ROW || score <= -FIVE_IN_A_ROW {
+			// a forced result, deeper won't change it
+			break
+		}
+		// the next depth takes several times as long as this one, don't start it if it can't finish
+		if time.Now().Add(4 * time.Since(iterationStart)).After(e.deadline) {
+			break
+		}
+	}
+	if e.tt != nil {
+		e.tt.printStats()
+	}
+	return bestMove
+}
+
+// searchRoot runs minimax on every move to the given depth. done is false when the time ran out
+// before every move was searched, the result is then incomplete.
+func (e *alphaBetaEngine) searchRoot(pos *position, moves [][2]int, depth int, player int, opponent int) ([2]int, int, bool) {
+	bestScore := math.MinInt32
+	bestMove := moves[0]
+	e.startEval(pos)
+	for _, move := range moves {
+		row, col := move[0], move[1]
+		e.playMove(pos, row, col, player)
+		// moves that can't beat the best one only need to prove that
+		score := e.minimax(pos, depth, bestScore, math.MaxInt32, false, player, opponent)
+		e.undoMove(pos, row, col)
+		if e.stopped {
+			return bestMove, bestScore, false
+		}
+		if score > bestScore {
+			bestScore = score
+			bestMove = move
+		}
+	}
+	return bestMove, bestScore, true
+}
+
+// moveFirst puts the move in front so the best move of the last depth is searched first
+func moveFirst(moves [][2]int, first [2]int) [][2]int {
+	ordered := [][2]int{first}
+	for _, move := range moves {
+		if move != first {
+			ordered = append(ordered, move)
+		}
+	}
+	return ordered
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMoveBudget(t *testing.T) {
+	cfg := searchConfig{safetyMargin: 3 * time.Second, maxMoveTime: 5 * time.Second}
+	uncapped := cfg
+	uncapped.maxMoveTime = 0
+	tests := []struct {
+		name       string
+		remaining  float64
+		moveNumber int
+		cfg        searchConfig
+		want       time.Duration
+	}{
+		{"less than the margin left", 2, 10, cfg, 0},
+		{"exactly the margin left", 3, 10, cfg, 0},
+		{"shared over the expected moves", 103, 10, cfg, 2500 * time.Millisecond},
+		{"capped by -move-time", 300, 0, cfg, 5 * time.Second},
+		{"no cap with -move-time 0", 300, 0, uncapped, 5940 * time.Millisecond},
+		{"at least minMovesLeft late in the game", 63, 45, cfg, 4 * time.Second},
+		{"past the expected moves", 33, 80, cfg, 2 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := moveBudget(tt.remaining, tt.moveNumber, tt.cfg); got != tt.want {
+				t.Errorf("moveBudget(%v, %d) = %v, want %v", tt.remaining, tt.moveNumber, got, tt.want)
+			}
+		})
+	}
+}
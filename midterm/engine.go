@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Engine picks a move for color on board[row][col]. maxDepth is how deep alpha-beta searches,
+// other engines may ignore it. With a deadline the engine stops searching by then, the zero time
+// searches to maxDepth or as much as the engine does without a clock. The error is for a board
+// the engine can't play on, one bigger than maxBoardSize.
+type Engine interface {
+	name() string
+	findBestMove(board [][]int, color string, maxDepth int, deadline time.Time) (Point, error)
+}
+
+// newEngine makes a fresh engine, an MCTS engine starts with an empty tree
+func newEngine(name string, cfg searchConfig) (Engine, error) {
+	switch name {
+	case "alphabeta":
+		return newAlphaBetaEngine(cfg), nil
+	case "mcts":
+		return newMCTSEngine(cfg.mctsIterations), nil
+	}
+	return nil, fmt.Errorf("unknown engine %q, use alphabeta or mcts", name)
+}
+
+// runMatch plays games between MCTS and alpha-beta on a board in memory, each with the same time
+// for every move, swapping colors after every game
+func runMatch(games int, moveTime time.Duration, cfg searchConfig, size int) {
+	wins := map[string]int{}
+	draws := 0
+	for g := 0; g < games; g++ {
+		mcts, _ := newEngine("mcts", cfg)
+		players := map[string]Engine{"black": mcts, "white": newAlphaBetaEngine(cfg)}
+		if g%2 == 1 {
+			players["black"], players["white"] = players["white"], players["black"]
+		}
+		result, moves := playMatchGame(players, moveTime, cfg.maxDepth, size)
+		if result == "" {
+			draws++
+			fmt.Printf("Match game %d: draw after %d moves\n", g+1, moves)
+		} else {
+			wins[players[result].name()]++
+			fmt.Printf("Match game %d: %s won as %s in %d moves\n", g+1, players[result].name(), result, moves)
+		}
+		fmt.Printf("Match score: mcts %d, alphabeta %d, draws %d\n", wins["mcts"], wins["alphabeta"], draws)
+	}
+}
+
+// playMatchGame plays one game and returns the color that won, empty for a draw, and the number of moves
+func playMatchGame(players map[string]Engine, moveTime time.Duration, maxDepth, size int) (string, int) {
+	board := make([][]int, size)
+	for i := range board {
+		board[i] = make([]int, size)
+	}
+	color := "black"
+	for moves := 1; ; moves++ {
+		p, err := players[color].findBestMove(makeCopy(board), color, maxDepth, time.Now().Add(moveTime))
+		if err != nil {
+			fmt.Printf("%s could not move: %v, it loses\n", players[color].name(), err)
+			return otherColor(color), moves
+		}
+		if !isEmptyCell(board, p) {
+			fmt.Printf("%s played %v which is not empty, it loses\n", players[color].name(), p)
+			return otherColor(color), moves
+		}
+		board[p.Row][p.Col] = toPlayer(color)
+		switch {
+		case hasWon(board, toPlayer(color)):
+			printBoardClearly(board)
+			return color, moves
+		case isBoardFull(board):
+			return "", moves
+		}
+		color = otherColor(color)
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestEnginesRefuseBoardsTooBig gives both engines a board that does not fit in a bitboard, they
+// have to answer with an error instead of panicking
+func TestEnginesRefuseBoardsTooBig(t *testing.T) {
+	board := make([][]int, maxBoardSize+1)
+	for i := range board {
+		board[i] = make([]int, maxBoardSize+1)
+	}
+	board[10][10] = BLACK
+	for _, e := range []Engine{newAlphaBetaEngine(defaultConfig().search), newMCTSEngine(10)} {
+		if _, err := e.findBestMove(board, "white", 2, time.Time{}); err == nil {
+			t.Errorf("%s played on a %dx%d board", e.name(), len(board), len(board))
+		}
+	}
+}
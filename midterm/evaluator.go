@@ -0,0 +1,280 @@
+package main
+
+// Cells of a line as the pattern matcher sees them, from one player's side
+const (
+	cellEmpty = iota
+	cellOwn
+	cellBlocked // the other player's stone or the edge of the board
+)
+
+// linePattern is a shape of stones on a line, _ is empty, X is the player's stone and O is blocked
+type linePattern struct {
+	shape string
+	score int
+	cells []int8
+	mask  uint64 // the X cells
+}
+
+// linePatterns are the shapes from best to worst. Gapped shapes like XX_XX count as fours and _X_XX_ as
+// an open three. Each stone only counts in the best shape it is part of, so segments aren't counted twice.
+var linePatterns = makePatterns([]linePattern{
+	{shape: "XXXXX", score: FIVE_IN_A_ROW},
+	{shape: "_XXXX_", score: OPEN_FOUR},
+	{shape: "XXXX_", score: CLOSED_FOUR},
+	{shape: "_XXXX", score: CLOSED_FOUR},
+	{shape: "XXX_X", score: CLOSED_FOUR},
+	{shape: "X_XXX", score: CLOSED_FOUR},
+	{shape: "XX_XX", score: CLOSED_FOUR},
+	{shape: "_XXX__", score: OPEN_THREE},
+	{shape: "__XXX_", score: OPEN_THREE},
+	{shape: "_XX_X_", score: OPEN_THREE},
+	{shape: "_X_XX_", score: OPEN_THREE},
+	{shape: "XXX__", score: CLOSED_THREE},
+	{shape: "__XXX", score: CLOSED_THREE},
+	{shape: "_XXX_", score: CLOSED_THREE},
+	{shape: "XX_X_", score: CLOSED_THREE},
+	{shape: "_X_XX", score: CLOSED_THREE},
+	{shape: "X_XX_", score: CLOSED_THREE},
+	{shape: "_XX_X", score: CLOSED_THREE},
+	{shape: "XX__X", score: CLOSED_THREE},
+	{shape: "X__XX", score: CLOSED_THREE},
+	{shape: "X_X_X", score: CLOSED_THREE},
+	{shape: "__XX__", score: OPEN_TWO},
+	{shape: "_XX___", score: OPEN_TWO},
+	{shape: "___XX_", score: OPEN_TWO},
+	{shape: "_X_X__", score: OPEN_TWO},
+	{shape: "__X_X_", score: OPEN_TWO},
+	{shape: "_X__X_", score: OPEN_TWO},
+	{shape: "XX___", score: CLOSED_TWO},
+	{shape: "___XX", score: CLOSED_TWO},
+	{shape: "_XX__", score: CLOSED_TWO},
+	{shape: "__XX_", score: CLOSED_TWO},
+	{shape: "X_X__", score: CLOSED_TWO},
+	{shape: "__X_X", score: CLOSED_TWO},
+	{shape: "_X_X_", score: CLOSED_TWO},
+	{shape: "X__X_", score: CLOSED_TWO},
+	{shape: "_X__X", score: CLOSED_TWO},
+	{shape: "X___X", score: CLOSED_TWO},
+})
+
+func makePatterns(patterns []linePattern) []linePattern {
+	for i := range patterns {
+		p := &patterns[i]
+		for j, r := range p.shape {
+			switch r {
+			case '_':
+				p.cells = append(p.cells, cellEmpty)
+			case 'X':
+				p.cells = append(p.cells, cellOwn)
+				p.mask |= 1 << j
+			default:
+				p.cells = append(p.cells, cellBlocked)
+			}
+		}
+	}
+	return patterns
+}
+
+// windowPatterns has the best pattern for every way five and six cells can look, the cells read as
+// a base 3 number, so finding the pattern at a cell is one lookup instead of trying them all
+var window5, window6 = makeWindows(5), makeWindows(6)
+
+func makeWindows(length int) []*linePattern {
+	codes := 1
+	for i := 0; i < length; i++ {
+		codes *= 3
+	}
+	windows := make([]*linePattern, codes)
+	cells := make([]int8, length)
+	for code := range windows {
+		for i, c := 0, code; i < length; i, c = i+1, c/3 {
+			cells[i] = int8(c % 3)
+		}
+		for j := range linePatterns {
+			if p := &linePatterns[j]; len(p.cells) == length && matchAt(cells, 0, p.cells) {
+				windows[code] = p
+				break
+			}
+		}
+	}
+	return windows
+}
+
+func windowCode(cells []int8, start, length int) int {
+	code := 0
+	for i := start + length - 1; i >= start; i-- {
+		code = code*3 + int(cells[i])
+	}
+	return code
+}
+
+// scoreLine adds up the patterns of the player in a line of at most 64 cells. It finds the best
+// pattern starting at every cell and takes them best first, skipping those that reuse a stone.
+// fives counts the five in a rows.
+func scoreLine(cells []int8) (score int, fives int) {
+	type match struct {
+		start   int
+		pattern *linePattern
+	}
+	var found [64]match
+	matches := found[:0]
+	for i := 0; i+5 <= len(cells); i++ {
+		best := window5[windowCode(cells, i, 5)]
+		if i+6 <= len(cells) {
+			if p := window6[windowCode(cells, i, 6)]; p != nil && (best == nil || p.score > best.score) {
+				best = p
+			}
+		}
+		if best != nil {
+			matches = append(matches, match{i, best})
+		}
+	}
+	// insertion sort, there are only a few
+	for i := 1; i < len(matches); i++ {
+		for j := i; j > 0 && matches[j].pattern.score > matches[j-1].pattern.score; j-- {
+			matches[j], matches[j-1] = matches[j-1], matches[j]
+		}
+	}
+	var used uint64
+	for _, m := range matches {
+		stones := m.pattern.mask << m.start
+		if used&stones != 0 {
+			continue
+		}
+		used |= stones
+		score += m.pattern.score
+		if m.pattern.score == FIVE_IN_A_ROW {
+			fives++
+		}
+	}
+	return score, fives
+}
+
+func matchAt(cells []int8, start int, pattern []int8) bool {
+	for j, c := range pattern {
+		if cells[start+j] != c {
+			return false
+		}
+	}
+	return true
+}
+
+// patternEval scores a position by the line patterns of both players. It keeps the score of every
+// line, so placing or removing a stone only rescores the four lines through it.
+type patternEval struct {
+	size   int
+	pos    *position   // the position it follows, update has to be called for every move made on it
+	lines  [4][][3]int // score of every line in every direction for both players
+	fives  [4][][3]int
+	total  [3]int
+	five   [3]int // five in a rows on the board
+	buffer []int8 // the stones of the line being scored
+	cells  []int8 // and the same line from one player's side
+}
+
+func newPatternEval(pos *position) *patternEval {
+	size := pos.size
+	e := &patternEval{size: size, pos: pos, buffer: make([]int8, size), cells: make([]int8, size)}
+	for d := range DIRECTIONS {
+		e.lines[d] = make([][3]int, 2*size-1)
+		e.fives[d] = make([][3]int, 2*size-1)
+	}
+	for d := range DIRECTIONS {
+		for i := range e.lines[d] {
+			if x, y, ok := e.lineStart(d, i); ok {
+				e.rescore(d, i, x, y)
+			}
+		}
+	}
+	return e
+}
+
+// lineStart is the first cell of line i in direction d. Rows and columns use the first size
+// indexes, the diagonals all 2*size-1.
+func (e *patternEval) lineStart(d, i int) (int, int, bool) {
+	size := e.size
+	switch d {
+	case 0: // x changes, line i is y = i
+		return 0, i, i < size
+	case 1: // y changes, line i is x = i
+		return i, 0, i < size
+	case 2: // x - y = i - (size-1)
+		diff := i - (size - 1)
+		return max(diff, 0), max(-diff, 0), true
+	default: // x + y = i, walking x up and y down
+		return max(i-(size-1), 0), min(i, size-1), true
+	}
+}
+
+// lineOf is the direction's line index through x, y
+func (e *patternEval) lineOf(d, x, y int) int {
+	switch d {
+	case 0:
+		return y
+	case 1:
+		return x
+	case 2:
+		return x - y + e.size - 1
+	default:
+		return x + y
+	}
+}
+
+// rescore recomputes line i of direction d starting at x, y and updates the totals
+func (e *patternEval) rescore(d, i, x, y int) {
+	line := e.pos.line(d, x, y, e.buffer)
+	stones := 0
+	for _, v := range line {
+		if v != EMPTY {
+			stones++
+		}
+	}
+	cells := e.cells[:len(line)]
+	for player := BLACK; player <= WHITE; player++ {
+		score, fives := 0, 0
+		if stones > 0 && len(line) >= 5 {
+			for j, v := range line {
+				switch int(v) {
+				case EMPTY:
+					cells[j] = cellEmpty
+				case player:
+					cells[j] = cellOwn
+				default:
+					cells[j] = cellBlocked
+				}
+			}
+			score, fives = scoreLine(cells)
+		}
+		e.total[player] += score - e.lines[d][i][player]
+		e.five[player] += fives - e.fives[d][i][player]
+		e.lines[d][i][player] = score
+		e.fives[d][i][player] = fives
+	}
+}
+
+// update rescores the lines through x, y after a stone was placed or removed there
+func (e *patternEval) update(x, y int) {
+	for d := range DIRECTIONS {
+		i := e.lineOf(d, x, y)
+		sx, sy, _ := e.lineStart(d, i)
+		e.rescore(d, i, sx, sy)
+	}
+}
+
+func (e *patternEval) hasWon(player int) bool {
+	return e.five[player] > 0
+}
+
+// score is the position from the player's side, like evaluate
+func (e *patternEval) score(player, opponent int) int {
+	return e.total[player] - e.total[opponent]
+}
+
+// startEval sets up the evaluation for a search of the position, with the classic evaluation
+// e.eval stays nil and minimax uses the old evaluate and hasWon
+func (e *alphaBetaEngine) startEval(pos *position) {
+	e.eval = nil
+	if e.cfg.eval == "pattern" {
+		e.eval = newPatternEval(pos)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestScoreLineShapes(t *testing.T) {
+	shapes := []struct {
+		line string
+		want int
+	}{
+		{"..XXXXX..", FIVE_IN_A_ROW},
+		{"..XXXX...", OPEN_FOUR},
+		{"OXXXX....", CLOSED_FOUR},
+		{"..XX.XX..", CLOSED_FOUR},
+		{"..XXX.X..", CLOSED_FOUR},
+		{"...XXX...", OPEN_THREE},
+		{"..X.XX...", OPEN_THREE},
+		{"O.XXX.O..", CLOSED_THREE},
+		{"OXXX.....", CLOSED_THREE},
+		{"...XX....", OPEN_TWO},
+		{"OXX......", CLOSED_TWO},
+		{"OXXXXO...", 0},
+	}
+	for _, shape := range shapes {
+		cells := make([]int8, len(shape.line))
+		for i, r := range shape.line {
+			cells[i] = map[rune]int8{'.': cellEmpty, 'X': cellOwn, 'O': cellBlocked}[r]
+		}
+		if got, _ := scoreLine(cells); got != shape.want {
+			t.Errorf("%s scores %d, want %d", shape.line, got, shape.want)
+		}
+	}
+}
+
+// TestIncrementalMatchesFresh plays and takes back random stones, after every step the kept
+// scores and five in a rows have to match scoring the whole board and hasWon
+func TestIncrementalMatchesFresh(t *testing.T) {
+	const size = 15
+	rng := rand.New(rand.NewSource(3))
+	for i, board := range benchPositions(20, size) {
+		e := newPatternEval(newPosition(board))
+		var played [][2]int
+		for step := 0; step < 60; step++ {
+			if len(played) > 0 && rng.Intn(3) == 0 {
+				last := played[len(played)-1]
+				played = played[:len(played)-1]
+				e.pos.undo(last[0], last[1])
+				e.update(last[0], last[1])
+			} else {
+				x, y := rng.Intn(size), rng.Intn(size)
+				if e.pos.at(x, y) != EMPTY {
+					continue
+				}
+				e.pos.play(x, y, 1+rng.Intn(2))
+				e.update(x, y)
+				played = append(played, [2]int{x, y})
+			}
+			board := e.pos.toBoard()
+			fresh := newPatternEval(newPosition(board))
+			if fresh.total != e.total || fresh.five != e.five || fresh.pos.hash != e.pos.hash {
+				t.Fatalf("position %d step %d: incremental %v, from scratch %v", i+1, step, e.total, fresh.total)
+			}
+			for p := BLACK; p <= WHITE; p++ {
+				if e.hasWon(p) != hasWon(board, p) || e.pos.hasFive(p) != hasWon(board, p) {
+					t.Errorf("position %d step %d: five in a row for %d is %v, on the bitboards %v, hasWon says %v",
+						i+1, step, p, e.hasWon(p), e.pos.hasFive(p), hasWon(board, p))
+				}
+			}
+		}
+	}
+}
+
+// TestSideAgreesWithClassic checks which side the pattern evaluation favours in the bench positions.
+// The classic evaluate only counts unbroken runs, so where the result hinges on a broken shape the
+// two disagree and the pattern evaluation is the one that sees it.
+func TestSideAgreesWithClassic(t *testing.T) {
+	// the positions the classic evaluation gets wrong, by number, with the side to move ahead
+	broken := map[int]bool{
+		// white's X_X twos in row 9 and column 6 tip a close position that evaluate scores as two loose stones each
+		6: true,
+		// white's X_XX in row 8 is a closed three, evaluate sees a two and a lone stone
+		8: false,
+		// black's XX_X in column 6 is an open three against white's closed three in row 8, evaluate sees a two
+		16: false,
+	}
+	for i, board := range benchPositions(20, 15) {
+		player := BLACK
+		if countStones(board, BLACK) > countStones(board, WHITE) {
+			player = WHITE
+		}
+		classic := evaluate(board, player, getOpponent(player)) > 0
+		pattern := newPatternEval(newPosition(board)).score(player, getOpponent(player)) > 0
+		want, ok := broken[i+1]
+		if !ok {
+			want = classic
+		}
+		if pattern != want {
+			t.Errorf("position %d: the pattern evaluation has the side to move ahead %v, want %v (classic %v)", i+1, pattern, want, classic)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+const (
+	// mctsExploration is the UCT constant, higher tries less visited moves more often
+	mctsExploration = 0.7
+	// mctsWidth is how many of the most threatening moves a node expands
+	mctsWidth = 20
+	// mctsSamples is how many random nearby cells a rollout move is picked from by threat score
+	mctsSamples = 6
+	// mctsRolloutPlies ends a rollout as a draw when nobody has won by then
+	mctsRolloutPlies = 80
+)
+
+// mctsNode is a position in the tree, reached by stone playing move
+type mctsNode struct {
+	move     [2]int
+	stone    int
+	parent   *mctsNode
+	children []*mctsNode
+	untried  [][2]int // moves not expanded yet, best first, nil until the node is first expanded
+	expanded bool
+	terminal bool // move made five in a row
+	visits   int
+	wins     float64 // for stone, a draw counts half
+}
+
+// mctsEngine is Monte Carlo tree search with UCT. It keeps its tree between our moves and goes on
+// from the node of the opponent's reply when it finds it.
+type mctsEngine struct {
+	iterations int // used when there is no deadline
+	rng        *rand.Rand
+	root       *mctsNode
+	rootBoard  [][]int
+}
+
+func newMCTSEngine(iterations int) *mctsEngine {
+	return &mctsEngine{iterations: iterations, rng: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+func (e *mctsEngine) name() string {
+	return "mcts"
+}
+
+func (e *mctsEngine) findBestMove(board [][]int, color string, maxDepth int, deadline time.Time) (Point, error) {
+	if err := checkBoardSize(len(board)); err != nil {
+		return noPoint, err
+	}
+	return e.bestMove(board, color, deadline), nil
+}
+
+func (e *mctsEngine) bestMove(board [][]int, color string, deadline time.Time) Point {
+	player := toPlayer(color)
+	opponent := getOpponent(player)
+	pos := newPosition(board)
+	if pos.count == 0 {
+		return firstMove(board)
+	}
+	moves := pos.moves()
+	if len(moves) == 0 {
+		return firstMove(board)
+	}
+	// a win now or the block of one needs no search
+	for _, stone := range []int{player, opponent} {
+		for _, move := range moves {
+			if pos.wouldFive(move[0], move[1], stone) {
+				fmt.Println("MCTS plays the five in a row at", move)
+				return pointOf(move)
+			}
+		}
+	}
+
+	root, reused := e.reuse(board, player)
+	if root == nil {
+		root = &mctsNode{move: [2]int{-1, -1}, stone: opponent}
+	}
+	e.root, e.rootBoard = root, makeCopy(board)
+
+	start := time.Now()
+	iterations := 0
+	// at least one iteration runs either way, it gives the root the child the move is picked from
+	for ; ; iterations++ {
+		if deadline.IsZero() {
+			if iterations >= max(e.iterations, 1) {
+				break
+			}
+		} else if iterations%64 == 0 && iterations > 0 && time.Now().After(deadline) {
+			break
+		}
+		e.iterate(pos, root)
+	}
+
+	best := root.children[0]
+	for _, child := range root.children {
+		if child.visits > best.visits {
+			best = child
+		}
+	}
+	fmt.Printf("MCTS: %d iterations in %v, %d visits reused, best %v visited %d times with %.2f wins\n", iterations,
+		time.Since(start).Round(time.Millisecond), reused, best.move, best.visits, best.wins/float64(max(best.visits, 1)))
+	return pointOf(best.move)
+}
+
+// reuse finds the node of the board in the tree of our last move, one or two moves below its root
+func (e *mctsEngine) reuse(board [][]int, player int) (*mctsNode, int) {
+	if e.root == nil || len(e.rootBoard) != len(board) {
+		return nil, 0
+	}
+	var placed [][2]int
+	for row := range board {
+		for col := range board[row] {
+			switch {
+			case e.rootBoard[row][col] == board[row][col]:
+			case e.rootBoard[row][col] == EMPTY:
+				placed = append(placed, [2]int{row, col})
+			default:
+				// a stone went away, it is another game
+				return nil, 0
+			}
+		}
+	}
+	node := e.root
+	for len(placed) > 0 {
+		stone := getOpponent(node.stone)
+		var next *mctsNode
+		for i, move := range placed {
+			if board[move[0]][move[1]] != stone {
+				continue
+			}
+			for _, child := range node.children {
+				if child.move == move {
+					next = child
+				}
+			}
+			placed = append(placed[:i], placed[i+1:]...)
+			break
+		}
+		if next == nil {
+			return nil, 0
+		}
+		node = next
+	}
+	if node.stone == player || node.terminal {
+		return nil, 0
+	}
+	node.parent = nil
+	return node, node.visits
+}
+
+// iterate runs one selection, expansion, rollout and backup from the root, leaving pos as it found it
+func (e *mctsEngine) iterate(pos *position, root *mctsNode) {
+	var played [][2]int
+	node := root
+	// selection
+	for node.expanded && len(node.untried) == 0 && len(node.children) > 0 && !node.terminal {
+		node = node.selectChild()
+		pos.play(node.move[0], node.move[1], node.stone)
+		played = append(played, node.move)
+	}
+	// expansion
+	if !node.terminal && !pos.full() {
+		if !node.expanded {
+			node.expand(pos)
+		}
+		if len(node.untried) > 0 {
+			move := node.untried[0]
+			node.untried = node.untried[1:]
+			child := &mctsNode{move: move, stone: getOpponent(node.stone), parent: node}
+			pos.play(move[0], move[1], child.stone)
+			played = append(played, move)
+			child.terminal = pos.hasFive(child.stone)
+			node.children = append(node.children, child)
+			node = child
+		}
+	}
+
+	winner := node.stone
+	if !node.terminal {
+		winner = e.rollout(pos, node)
+	}
+	for i := len(played) - 1; i >= 0; i-- {
+		pos.undo(played[i][0], played[i][1])
+	}
+	// backup
+	for n := node; n != nil; n = n.parent {
+		n.visits++
+		switch winner {
+		case n.stone:
+			n.wins++
+		case EMPTY:
+			n.wins += 0.5
+		}
+	}
+}
+
+// selectChild is the child with the best UCT value
+func (n *mctsNode) selectChild() *mctsNode {
+	var best *mctsNode
+	bestValue := math.Inf(-1)
+	logVisits := math.Log(float64(n.visits))
+	for _, child := range n.children {
+		value := child.wins/float64(child.visits) + mctsExploration*math.Sqrt(logVisits/float64(child.visits))
+		if value > bestValue {
+			best, bestValue = child, value
+		}
+	}
+	return best
+}
+
+// expand lists the moves of the node's position, the most threatening mctsWidth first
+func (n *mctsNode) expand(pos *position) {
+	n.expanded = true
+	stone := getOpponent(n.stone)
+	moves := pos.moves()
+	scores := make(map[[2]int]int, len(moves))
+	for _, move := range moves {
+		scores[move] = threatScore(pos, move[0], move[1], stone)
+	}
+	sort.SliceStable(moves, func(i, j int) bool {
+		return scores[moves[i]] > scores[moves[j]]
+	})
+	if len(moves) > mctsWidth {
+		moves = moves[:mctsWidth]
+	}
+	n.untried = moves
+}
+
+// rollout plays the game out from the node and returns the winner, EMPTY for a draw
+func (e *mctsEngine) rollout(pos *position, node *mctsNode) int {
+	var played [][2]int
+	defer func() {
+		for i := len(played) - 1; i >= 0; i-- {
+			pos.undo(played[i][0], played[i][1])
+		}
+	}()
+	noMove := [2]int{-1, -1}
+	stone := getOpponent(node.stone)
+	ownLast, theirLast := noMove, node.move
+	if node.parent != nil {
+		ownLast = node.parent.move
+	}
+	for ply := 0; ply < mctsRolloutPlies && !pos.full(); ply++ {
+		move, ok := e.rolloutMove(pos, stone, ownLast, theirLast)
+		if !ok {
+			break
+		}
+		pos.play(move[0], move[1], stone)
+		played = append(played, move)
+		if pos.hasFive(stone) {
+			return stone
+		}
+		ownLast, theirLast = theirLast, move
+		stone = getOpponent(stone)
+	}
+	return EMPTY
+}
+
+// rolloutMove wins when the stone's last move made a four, blocks the four the other side's last
+// move made, and otherwise takes the most threatening of a few random nearby cells
+func (e *mctsEngine) rolloutMove(pos *position, stone int, ownLast, theirLast [2]int) ([2]int, bool) {
+	if ownLast[0] >= 0 {
+		if cell, ok := pos.fiveCellThrough(ownLast[0], ownLast[1], stone); ok {
+			return cell, true
+		}
+	}
+	if theirLast[0] >= 0 {
+		if cell, ok := pos.fiveCellThrough(theirLast[0], theirLast[1], getOpponent(stone)); ok {
+			return cell, true
+		}
+	}
+	moves := pos.moves()
+	if len(moves) == 0 {
+		return [2]int{}, false
+	}
+	best, bestScore := moves[e.rng.Intn(len(moves))], -1
+	for i := 0; i < mctsSamples; i++ {
+		move := moves[e.rng.Intn(len(moves))]
+		if score := threatScore(pos, move[0], move[1], stone); score > bestScore {
+			best, bestScore = move, score
+		}
+	}
+	return best, true
+}
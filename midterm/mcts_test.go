@@ -0,0 +1,134 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMCTSWithoutIterationsStillMoves(t *testing.T) {
+	board := make([][]int, 15)
+	for i := range board {
+		board[i] = make([]int, 15)
+	}
+	board[7][7] = BLACK
+
+	for _, iterations := range []int{0, -1, 1} {
+		move, err := newMCTSEngine(iterations).findBestMove(board, "white", 0, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !isEmptyCell(board, move) {
+			t.Errorf("with %d iterations MCTS played %v, which is not an empty cell", iterations, move)
+		}
+	}
+}
+
+func TestMCTSReuse(t *testing.T) {
+	board := make([][]int, 15)
+	for i := range board {
+		board[i] = make([]int, 15)
+	}
+	board[7][7] = BLACK
+	e := newMCTSEngine(2000)
+	move, err := e.findBestMove(board, "white", 0, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ours *mctsNode
+	for _, child := range e.root.children {
+		if child.move == [2]int{move.Row, move.Col} {
+			ours = child
+		}
+	}
+	if ours == nil || len(ours.children) == 0 {
+		t.Fatalf("the tree has nothing below our move %v", move)
+	}
+	reply := ours.children[0]
+	next := makeCopy(board)
+	next[move.Row][move.Col] = WHITE
+	next[reply.move[0]][reply.move[1]] = BLACK
+
+	node, reused := e.reuse(next, WHITE)
+	if node != reply || reused != reply.visits || node.parent != nil {
+		t.Errorf("after the reply %v reuse gave %p with %d visits, want the reply's node %p with %d", reply.move, node, reused, reply, reply.visits)
+	}
+
+	other := make([][]int, 15)
+	for i := range other {
+		other[i] = make([]int, 15)
+	}
+	other[3][3] = BLACK
+	if node, _ := e.reuse(other, WHITE); node != nil {
+		t.Errorf("reuse found a node of the last game in a new one")
+	}
+	if node, _ := e.reuse(make([][]int, 9), WHITE); node != nil {
+		t.Errorf("reuse found a node on a board of another size")
+	}
+}
+
+func TestMCTSPlaysFive(t *testing.T) {
+	tests := []struct {
+		name string
+		rows []string
+		want Point
+	}{
+		{
+			name: "win",
+			rows: []string{
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				"..X.X.X.X......",
+				"...OOOOX.......",
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+			},
+			want: Point{Row: 7, Col: 2},
+		},
+		{
+			name: "block",
+			rows: []string{
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				"..XXXXO........",
+				"...............",
+				"...............",
+				".........O.....",
+				"...............",
+				"...............",
+				".............O.",
+				"...............",
+				"...............",
+			},
+			want: Point{Row: 6, Col: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board, err := parsePosition(tt.rows)
+			if err != nil {
+				t.Fatal(err)
+			}
+			move, err := newMCTSEngine(100).findBestMove(board, "white", 0, time.Time{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if move != tt.want {
+				t.Errorf("MCTS played %v, want %v", move, tt.want)
+			}
+		})
+	}
+}
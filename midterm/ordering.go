@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// killerBonus puts killer moves after fours and blocks of fours but before everything else
+const killerBonus = OPEN_FOUR
+
+// resetOrdering forgets the killers and the history, every move starts afresh. Positions searched
+// at the same depth are usually siblings where the same killer reply works again.
+func (e *alphaBetaEngine) resetOrdering(size, maxDepth int) {
+	e.killers = make([][2][2]int, maxDepth+2)
+	for i := range e.killers {
+		e.killers[i] = [2][2]int{{-1, -1}, {-1, -1}}
+	}
+	e.history = make([][][3]int, size)
+	for i := range e.history {
+		e.history[i] = make([][3]int, size)
+	}
+}
+
+// recordCutoff remembers a move that made the search cut off
+func (e *alphaBetaEngine) recordCutoff(move [2]int, depth, stone int) {
+	if depth < len(e.killers) && e.killers[depth][0] != move {
+		e.killers[depth][1] = e.killers[depth][0]
+		e.killers[depth][0] = move
+	}
+	if move[0] < len(e.history) {
+		e.history[move[0]][move[1]][stone] += depth * depth
+	}
+}
+
+// lineScore is what the player's lines through x, y would be worth with a stone there
+func lineScore(pos *position, x, y, player int) int {
+	score := 0
+	for _, dir := range DIRECTIONS {
+		dx, dy := dir[0], dir[1]
+		count, openEnds := 1, 0
+		for _, sign := range []int{1, -1} {
+			for i := 1; i < 5; i++ {
+				nx, ny := x+sign*dx*i, y+sign*dy*i
+				if !pos.inside(nx, ny) {
+					break
+				}
+				stone := pos.at(nx, ny)
+				if stone == player {
+					count++
+					continue
+				}
+				if stone == EMPTY {
+					openEnds++
+				}
+				break
+			}
+		}
+		score += getPatternScore(count, openEnds)
+	}
+	return score
+}
+
+// threatScore rates a move by the lines it makes for the stone and, at half weight, the lines of
+// the other side it blocks, using the same pattern scores as the evaluation
+func threatScore(pos *position, x, y, stone int) int {
+	return lineScore(pos, x, y, stone) + lineScore(pos, x, y, getOpponent(stone))/2
+}
+
+// orderMoves sorts the moves the stone could play with the table move first, then by threat score
+// with a bonus for killers and the history. With prune it keeps only the best top N.
+func (e *alphaBetaEngine) orderMoves(pos *position, moves [][2]int, depth, stone int, ttMove [2]int, prune bool) [][2]int {
+	type scoredMove struct {
+		move  [2]int
+		score int
+	}
+	scored := make([]scoredMove, 0, len(moves))
+	for _, move := range moves {
+		x, y := move[0], move[1]
+		if pos.at(x, y) != EMPTY {
+			continue
+		}
+		score := threatScore(pos, x, y, stone)
+		if e.history != nil {
+			score += e.history[x][y][stone]
+		}
+		if depth < len(e.killers) && (e.killers[depth][0] == move || e.killers[depth][1] == move) {
+			score += killerBonus
+		}
+		if move == ttMove {
+			score = 1 << 40
+		}
+		scored = append(scored, scoredMove{move, score})
+	}
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].score > scored[j].score
+	})
+	if prune && e.cfg.topN > 0 && len(scored) > e.cfg.topN {
+		scored = scored[:e.cfg.topN]
+	}
+	ordered := make([][2]int, len(scored))
+	for i, s := range scored {
+		ordered[i] = s.move
+	}
+	return ordered
+}
+
+// runBenchOrdering searches the bench positions to the same depth with the table and the move
+// ordering switched on one after the other and compares the nodes searched
+func runBenchOrdering(cfg searchConfig, size int) {
+	searchDepth := cfg.depth
+	type setup struct {
+		name     string
+		tt       bool
+		ordering bool
+		topN     int
+	}
+	setups := []setup{
+		{"plain", false, false, 0},
+		{"tt", true, false, 0},
+		{"tt+order", true, true, 0},
+		{fmt.Sprintf("tt+order+top%d", max(cfg.topN, 1)), true, true, max(cfg.topN, 1)},
+	}
+	if cfg.topN <= 0 {
+		setups[3] = setup{"tt+order+top10", true, true, 10}
+	}
+
+	fmt.Printf("Nodes searched to depth %d, * marks a best score different from the plain search\n", searchDepth)
+	fmt.Print("pos")
+	for _, s := range setups {
+		fmt.Printf(" %16s", s.name)
+	}
+	fmt.Println()
+	totalNodes := make([]int, len(setups))
+	totalTime := make([]time.Duration, len(setups))
+	for i, board := range benchPositions(10, size) {
+		player := BLACK
+		if countStones(board, BLACK) > countStones(board, WHITE) {
+			player = WHITE
+		}
+		moves := generateMoves(board)
+		pos := newPosition(board)
+
+		fmt.Printf("%3d", i+1)
+		var plainScore int
+		for j, s := range setups {
+			setupCfg := cfg
+			setupCfg.useOrdering, setupCfg.topN = s.ordering, s.topN
+			e := newAlphaBetaEngine(setupCfg)
+			if s.tt {
+				e.tt = newTranspositionTable(cfg.ttBits)
+			}
+			e.resetOrdering(len(board), searchDepth)
+			rootMoves := moves
+			if s.ordering {
+				rootMoves = e.orderMoves(pos, moves, searchDepth+1, player, [2]int{-1, -1}, false)
+			}
+			start := time.Now()
+			_, score, _ := e.searchRoot(pos, rootMoves, searchDepth, player, getOpponent(player))
+			totalTime[j] += time.Since(start)
+			totalNodes[j] += e.nodes
+
+			mark := " "
+			if j == 0 {
+				plainScore = score
+			} else if score != plainScore {
+				mark = "*"
+			}
+			fmt.Printf(" %15d%s", e.nodes, mark)
+		}
+		fmt.Println()
+	}
+	fmt.Print("all")
+	for j := range setups {
+		fmt.Printf(" %16d", totalNodes[j])
+	}
+	fmt.Print("\ntime")
+	for j := range setups {
+		fmt.Printf(" %15v", totalTime[j].Round(time.Millisecond))
+	}
+	fmt.Println()
+}
@@ -0,0 +1,46 @@
+package main
+
+import "fmt"
+
+// Point is a cell of the board. Row is the index into gameboard the server sends and the
+// engine's board[row][col] alike, Col the index into a row. The move URL takes x, y with
+// x the column and y the row, so URL and pointFromURL are the only places the order flips.
+type Point struct {
+	Row int
+	Col int
+}
+
+// noPoint is the point off the board for when there is no move
+var noPoint = Point{-1, -1}
+
+// pointFromURL is the point of the x, y of a move URL
+func pointFromURL(x, y int) Point {
+	return Point{Row: y, Col: x}
+}
+
+// URL is the x, y the server takes for the point
+func (p Point) URL() (x, y int) {
+	return p.Col, p.Row
+}
+
+func (p Point) String() string {
+	return fmt.Sprintf("(row %d, col %d)", p.Row, p.Col)
+}
+
+// pointOf is the point of an engine move, which is {row, col} like the engine's boards
+func pointOf(move [2]int) Point {
+	return Point{Row: move[0], Col: move[1]}
+}
+
+// onBoard tells if the point is inside a board of the size
+func (p Point) onBoard(size int) bool {
+	return p.Row >= 0 && p.Row < size && p.Col >= 0 && p.Col < size
+}
+
+// gameboardAt is the stone at the point of the board the server sends
+func gameboardAt(gameboard [][]int, p Point) int {
+	if p.Row < 0 || p.Row >= len(gameboard) || p.Col < 0 || p.Col >= len(gameboard[p.Row]) {
+		return -1
+	}
+	return gameboard[p.Row][p.Col]
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPointURL(t *testing.T) {
+	tests := []struct {
+		p    Point
+		x, y int
+	}{
+		{Point{Row: 11, Col: 3}, 3, 11},
+		{Point{Row: 0, Col: 14}, 14, 0},
+		{Point{Row: 7, Col: 7}, 7, 7},
+	}
+	for _, tt := range tests {
+		x, y := tt.p.URL()
+		if x != tt.x || y != tt.y {
+			t.Errorf("%v goes to the URL as %d,%d, want %d,%d", tt.p, x, y, tt.x, tt.y)
+		}
+		if back := pointFromURL(x, y); back != tt.p {
+			t.Errorf("URL %d,%d comes back as %v, want %v", x, y, back, tt.p)
+		}
+	}
+}
+
+// coordCase is a position the engine plays one move in. The board is written like the server
+// sends it, rows down and columns across, and ok looks at the game after the move.
+type coordCase struct {
+	name     string
+	rows     []string
+	color    string
+	think    func(Game) Point
+	opponent func() opponent
+	ok       func(after Game, p Point) bool
+}
+
+// TestMovesLandOnIntendedCell plays a move from every path the engine picks moves by against the
+// local server over HTTP and checks the stone ends up on the cell the engine meant
+func TestMovesLandOnIntendedCell(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.server.opponent, cfg.server.color, cfg.server.games, cfg.server.boardSize = "random", "black", 0, 15
+	cfg.search.depth, cfg.search.maxMoveTime = 2, time.Second
+
+	rng := rand.New(rand.NewSource(1))
+	// plays in the corners, away from everything the cases look at
+	corners := func() opponent {
+		return &scriptedOpponent{moves: []Point{{0, 0}, {0, 14}, {14, 0}, {14, 14}}, fallback: &randomOpponent{rng: rng}}
+	}
+	withThreats := func(threats, fixed bool) func(Game) Point {
+		search := cfg.search
+		search.useThreats, search.fixedDepth = threats, fixed
+		return engineMove(newAlphaBetaEngine(search), search)
+	}
+	at := func(p Point) func(Game, Point) bool {
+		return func(after Game, got Point) bool {
+			return got == p && gameboardAt(after.Gameboard, p) == toPlayer(after.Color)
+		}
+	}
+	// fours counts the cells the player could make five on, so a four gives one and an open four two
+	fours := func(after Game, player int) int {
+		return len(fiveCells(makeCopy(after.Gameboard), player))
+	}
+	empty := make([]string, 15)
+	for i := range empty {
+		empty[i] = "..............."
+	}
+
+	cases := []coordCase{
+		{name: "first move", rows: empty, color: "black", think: withThreats(true, true), ok: at(Point{7, 7})},
+		{
+			name: "winning move",
+			rows: []string{
+				"...............",
+				"...............",
+				"...............",
+				".OXXXX.........",
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				"..........O.O..",
+				"...............",
+				"..............O",
+				"...............",
+				"...............",
+			},
+			color: "black", think: withThreats(true, true), ok: at(Point{3, 6}),
+		},
+		{
+			name: "blocking move",
+			rows: []string{
+				"...............",
+				".............X.",
+				"...............",
+				".........X.....",
+				".........O.....",
+				".........O.....",
+				".........O.....",
+				".........O.....",
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				"..X.X..........",
+				"...............",
+				"...............",
+			},
+			color: "black", think: withThreats(true, true), ok: at(Point{8, 9}),
+		},
+		{
+			name: "threat search attack makes a four",
+			rows: []string{
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				".....XXX.......",
+				"........X......",
+				"........X......",
+				"........X......",
+				"...............",
+				"...............",
+				"...............",
+				".O.O...O...O...",
+				".....O...O.....",
+				"...............",
+			},
+			color: "black", think: withThreats(true, true),
+			ok: func(after Game, p Point) bool { return fours(after, BLACK) > 0 },
+		},
+		{
+			name: "threat search defence leaves white no forced win",
+			rows: []string{
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				".....OOO.......",
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				".X.X...........",
+				".....X.........",
+				"...............",
+			},
+			color: "black", think: withThreats(true, true),
+			ok: func(after Game, p Point) bool {
+				_, kind, _ := newAlphaBetaEngine(cfg.search).threatWin(makeCopy(after.Gameboard), WHITE, cfg.search.threatNodes)
+				return kind == ""
+			},
+		},
+	}
+	openThree := []string{
+		"...............",
+		"...............",
+		".....XXX.......",
+		"...............",
+		"...............",
+		"...............",
+		"...............",
+		"...............",
+		"...............",
+		"...............",
+		".............O.",
+		"..........O....",
+		"............O..",
+		"...............",
+		"...............",
+	}
+	openFour := func(after Game, p Point) bool { return fours(after, BLACK) >= 2 }
+	cases = append(cases,
+		coordCase{name: "search to a fixed depth makes an open four", rows: openThree, color: "black", think: withThreats(false, true), ok: openFour},
+		coordCase{name: "search with a time budget makes an open four", rows: openThree, color: "black", think: withThreats(false, false), ok: openFour},
+		coordCase{
+			name: "fallback move",
+			rows: []string{
+				"...............",
+				"...............",
+				"...............",
+				"..........X....",
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+			},
+			color: "white",
+			think: func(game Game) Point { return fallbackMove(convertGameboard(game.Gameboard)) },
+			ok:    at(Point{4, 9}),
+		},
+		coordCase{
+			name:  "engine opponent on the server wins with its four",
+			rows:  cases[2].rows,
+			color: "black",
+			think: func(Game) Point { return Point{14, 13} },
+			opponent: func() opponent {
+				return &engineOpponent{engine: newAlphaBetaEngine(cfg.search), depth: 2, fallback: &randomOpponent{rng: rng}}
+			},
+			ok: func(after Game, p Point) bool {
+				return after.GameStatus == "WHITEWON" && gameboardAt(after.Gameboard, Point{8, 9}) == WHITE
+			},
+		},
+	)
+
+	server := newGameServer(1, cfg.server)
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	client := newHTTPClient(ts.URL, student_id, 10*time.Second, 0, 10*time.Millisecond)
+	ctx := context.Background()
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			board, err := parsePosition(c.rows)
+			if err != nil {
+				t.Fatal(err)
+			}
+			game, err := client.Start(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			server.mu.Lock()
+			g := server.games[game.GameID]
+			g.board, g.color, g.turn = board, c.color, c.color
+			g.remaining = 60
+			g.opponent = corners()
+			if c.opponent != nil {
+				g.opponent = c.opponent()
+			}
+			server.mu.Unlock()
+
+			game, err = client.State(ctx, game.GameID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := c.think(game)
+			if _, err := client.Move(ctx, game.GameID, p); err != nil {
+				t.Fatal(err)
+			}
+			after := waitForReply(t, client, game.GameID)
+			if !c.ok(after, p) {
+				t.Errorf("the engine played %v", p)
+			}
+		})
+	}
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// opponent plays the other side on the local server. It gets the gameboard and returns the point it wants to play.
+type opponent interface {
+	name() string
+	play(board [][]int, color string) Point
+}
+
+// randomOpponent plays a random empty cell, next to the stones already on the board when it can
+type randomOpponent struct {
+	rng *rand.Rand
+}
+
+func (o *randomOpponent) name() string {
+	return "random"
+}
+
+func (o *randomOpponent) play(board [][]int, color string) Point {
+	size := len(board)
+	var near, empty []Point
+	for row := 0; row < size; row++ {
+		for col := 0; col < size; col++ {
+			p := Point{Row: row, Col: col}
+			if board[row][col] != EMPTY {
+				continue
+			}
+			empty = append(empty, p)
+			if hasNeighbor(board, p) {
+				near = append(near, p)
+			}
+		}
+	}
+	if len(near) > 0 {
+		empty = near
+	}
+	if len(empty) == 0 {
+		return noPoint
+	}
+	return empty[o.rng.Intn(len(empty))]
+}
+
+func hasNeighbor(board [][]int, p Point) bool {
+	for dr := -1; dr <= 1; dr++ {
+		for dc := -1; dc <= 1; dc++ {
+			n := Point{Row: p.Row + dr, Col: p.Col + dc}
+			if (dr != 0 || dc != 0) && n.onBoard(len(board)) && board[n.Row][n.Col] != EMPTY {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// engineOpponent is our own engine playing the other side. Its move goes to the server
+// the same way the client sends it, so both sides behave alike.
+type engineOpponent struct {
+	engine   Engine
+	depth    int
+	fallback *randomOpponent
+}
+
+func (o *engineOpponent) name() string {
+	return o.engine.name() + " engine"
+}
+
+func (o *engineOpponent) play(board [][]int, color string) Point {
+	move, err := o.engine.findBestMove(makeCopy(board), color, o.depth, time.Time{})
+	if err != nil {
+		fmt.Println("Engine opponent could not move, playing randomly instead:", err)
+		return o.fallback.play(board, color)
+	}
+	if !isEmptyCell(board, move) {
+		fmt.Println("Engine opponent picked an occupied cell, playing randomly instead")
+		return o.fallback.play(board, color)
+	}
+	return move
+}
+
+// scriptedOpponent plays the moves from a file in order, one x,y per line in the order of the
+// move URL, and plays randomly once the script runs out or a scripted cell is already taken
+type scriptedOpponent struct {
+	moves    []Point
+	next     int
+	fallback *randomOpponent
+}
+
+func readScript(filePath string) ([]Point, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var moves []Point
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var x, y int
+		if _, err := fmt.Sscanf(strings.ReplaceAll(text, " ", ""), "%d,%d", &x, &y); err != nil {
+			return nil, fmt.Errorf("line %d: expected x,y, got %q", line, text)
+		}
+		moves = append(moves, pointFromURL(x, y))
+	}
+	return moves, scanner.Err()
+}
+
+func (o *scriptedOpponent) name() string {
+	return "script"
+}
+
+func (o *scriptedOpponent) play(board [][]int, color string) Point {
+	for o.next < len(o.moves) {
+		move := o.moves[o.next]
+		o.next++
+		if isEmptyCell(board, move) {
+			return move
+		}
+		fmt.Printf("Scripted move %v is not playable, skipping it\n", move)
+	}
+	return o.fallback.play(board, color)
+}
+
+// newOpponent builds a fresh opponent for every game so scripts start over
+func newOpponent(rng *rand.Rand, cfg serverConfig) (opponent, error) {
+	random := &randomOpponent{rng: rng}
+	switch cfg.opponent {
+	case "random":
+		return random, nil
+	case "engine":
+		engine, err := newEngine(cfg.opponentEngine, cfg.search)
+		if err != nil {
+			return nil, err
+		}
+		return &engineOpponent{engine: engine, depth: cfg.search.depth, fallback: random}, nil
+	case "script":
+		moves, err := readScript(cfg.script)
+		if err != nil {
+			return nil, err
+		}
+		return &scriptedOpponent{moves: moves, fallback: random}, nil
+	}
+	return nil, fmt.Errorf("unknown opponent %q, use random, engine or script", cfg.opponent)
+}
+
+func isEmptyCell(board [][]int, p Point) bool {
+	return gameboardAt(board, p) == EMPTY
+}
+
+// serverGame is one game on the local server, the student plays color against the opponent
+type serverGame struct {
+	id          int
+	color       string
+	turn        string
+	status      string
+	board       [][]int // gameboard[row][col], like the remote server sends it
+	remaining   float64 // seconds left on the student's clock
+	turnStarted time.Time
+	opponent    opponent
+	delay       time.Duration // how long the opponent thinks before every move
+}
+
+// gameServer stands in for the remote game server with the same endpoints and JSON
+type gameServer struct {
+	mu      sync.Mutex
+	games   map[int]*serverGame
+	nextID  int
+	started int
+	rng     *rand.Rand
+	cfg     serverConfig
+}
+
+func newGameServer(seed int64, cfg serverConfig) *gameServer {
+	return &gameServer{games: make(map[int]*serverGame), nextID: 1, rng: rand.New(rand.NewSource(seed)), cfg: cfg}
+}
+
+func otherColor(color string) string {
+	if color == "black" {
+		return "white"
+	}
+	return "black"
+}
+
+// tick takes the time the student has been thinking off their clock, losing the game when it runs out
+func (g *serverGame) tick(now time.Time) {
+	if g.status != "ONGOING" || g.turn != g.color {
+		return
+	}
+	g.remaining -= now.Sub(g.turnStarted).Seconds()
+	g.turnStarted = now
+	if g.remaining <= 0 {
+		g.remaining = 0
+		g.status = strings.ToUpper(otherColor(g.color)) + "WON"
+		fmt.Printf("Game %d: %s ran out of time\n", g.id, g.color)
+	}
+}
+
+// place puts a stone for the color and checks for five in a row or a full board
+func (g *serverGame) place(p Point, color string) {
+	g.board[p.Row][p.Col] = toPlayer(color)
+	switch {
+	case hasWon(g.board, toPlayer(color)):
+		g.status = strings.ToUpper(color) + "WON"
+	case isBoardFull(g.board):
+		g.status = "DRAW"
+	default:
+		g.turn = otherColor(color)
+	}
+	if g.status != "ONGOING" {
+		fmt.Printf("Game %d finished: %s\n", g.id, g.status)
+	}
+}
+
+// opponentMove lets the opponent play if it is its turn. It is called with s.mu held and returns
+// straight away, the opponent thinks on a copy of the board with the server unlocked so other
+// requests are answered meanwhile, and takes the lock again to put its stone down.
+func (s *gameServer) opponentMove(g *serverGame) {
+	if g.status != "ONGOING" || g.turn == g.color {
+		return
+	}
+	board, color := makeCopy(g.board), g.turn
+	go func() {
+		time.Sleep(g.delay)
+		p := g.opponent.play(board, color)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		g.opponentPlays(p, color)
+	}()
+}
+
+// opponentPlays puts down the opponent's stone, its turn only ended once it has played
+func (g *serverGame) opponentPlays(p Point, color string) {
+	if !isEmptyCell(g.board, p) {
+		// nowhere left to play
+		g.status = "DRAW"
+		return
+	}
+	g.place(p, color)
+	g.turnStarted = time.Now()
+}
+
+func (g *serverGame) response(requestStatus string) Game {
+	return Game{
+		Color:         g.color,
+		GameID:        g.id,
+		GameStatus:    g.status,
+		TimeRemaining: g.remaining,
+		Turn:          g.turn,
+		Gameboard:     makeCopy(g.board),
+		RequestStatus: requestStatus,
+	}
+}
+
+func writeGame(w http.ResponseWriter, code int, game Game) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(game); err != nil {
+		fmt.Println("Error writing response:", err)
+	}
+}
+
+// ServeHTTP handles /{student_id}/start, /{student_id}/{game_id} and /{student_id}/{game_id}/{x}/{y}
+func (s *gameServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) == 2 && parts[1] == "start" {
+		code, game := s.start()
+		writeGame(w, code, game)
+		return
+	}
+	if len(parts) != 2 && len(parts) != 4 {
+		http.NotFound(w, r)
+		return
+	}
+	id, err := strconv.Atoi(parts[1])
+	if err != nil {
+		writeGame(w, http.StatusNotFound, Game{RequestStatus: "UNKNOWN_GAME"})
+		return
+	}
+	if len(parts) == 2 {
+		code, game := s.state(id)
+		writeGame(w, code, game)
+		return
+	}
+	x, errX := strconv.Atoi(parts[2])
+	y, errY := strconv.Atoi(parts[3])
+	if errX != nil || errY != nil {
+		// not a number can never be on the board
+		x, y = -1, -1
+	}
+	code, game := s.play(id, pointFromURL(x, y))
+	writeGame(w, code, game)
+}
+
+// state is the game as it is now, with the student's clock brought up to date
+func (s *gameServer) state(id int) (int, Game) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	game, ok := s.games[id]
+	if !ok {
+		return http.StatusNotFound, Game{GameID: id, RequestStatus: "UNKNOWN_GAME"}
+	}
+	game.tick(time.Now())
+	return http.StatusOK, game.response("OK")
+}
+
+// play puts the student's stone on the point and lets the opponent answer, the answer shows up
+// in the state once the opponent has played
+func (s *gameServer) play(id int, p Point) (int, Game) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	game, ok := s.games[id]
+	if !ok {
+		return http.StatusNotFound, Game{GameID: id, RequestStatus: "UNKNOWN_GAME"}
+	}
+	game.tick(time.Now())
+	switch {
+	case game.status != "ONGOING":
+		return http.StatusBadRequest, game.response("GAME_OVER")
+	case game.turn != game.color:
+		return http.StatusBadRequest, game.response("NOT_YOUR_TURN")
+	case !isEmptyCell(game.board, p):
+		fmt.Printf("Game %d: invalid move %v\n", game.id, p)
+		return http.StatusBadRequest, game.response("INVALID_MOVE")
+	}
+	game.place(p, game.color)
+	s.opponentMove(game)
+	return http.StatusOK, game.response("OK")
+}
+
+// start begins a new game, or tells the client to leave once it has played its games
+func (s *gameServer) start() (int, Game) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cfg.games > 0 && s.started >= s.cfg.games {
+		return http.StatusOK, Game{GameStatus: "LEAVE", RequestStatus: "OK"}
+	}
+	// the opponent plays outside the lock, so it gets a random source of its own
+	opp, err := newOpponent(rand.New(rand.NewSource(s.rng.Int63())), s.cfg)
+	if err != nil {
+		fmt.Println("Error creating opponent:", err)
+		return http.StatusInternalServerError, Game{RequestStatus: err.Error()}
+	}
+	color := s.cfg.color
+	if color == "random" {
+		color = []string{"black", "white"}[s.rng.Intn(2)]
+	}
+	board := make([][]int, s.cfg.boardSize)
+	for i := range board {
+		board[i] = make([]int, s.cfg.boardSize)
+	}
+	game := &serverGame{
+		id:          s.nextID,
+		color:       color,
+		turn:        "black",
+		status:      "ONGOING",
+		board:       board,
+		remaining:   s.cfg.clock,
+		turnStarted: time.Now(),
+		opponent:    opp,
+		delay:       s.cfg.opponentDelay,
+	}
+	s.games[game.id] = game
+	s.nextID++
+	s.started++
+	fmt.Printf("Game %d started, we play %s against the %s opponent\n", game.id, color, opp.name())
+
+	s.opponentMove(game)
+	return http.StatusOK, game.response("OK")
+}
+
+// startLocalServer runs the handler on a free local port and returns its URL
+func startLocalServer(handler http.Handler) (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	go func() {
+		err := http.Serve(listener, handler)
+		if err != nil {
+			fmt.Println("Local server stopped:", err)
+		}
+	}()
+	return "http://" + listener.Addr().String(), nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// boardFromRows reads a gameboard written row by row, X for black, O for white and . for empty
+func boardFromRows(rows []string) [][]int {
+	board := make([][]int, len(rows))
+	for y, row := range rows {
+		board[y] = make([]int, len(row))
+		for x, r := range row {
+			switch r {
+			case 'X':
+				board[y][x] = BLACK
+			case 'O':
+				board[y][x] = WHITE
+			}
+		}
+	}
+	return board
+}
+
+// waitForReply polls the game until the opponent has answered the last move or the game is over
+func waitForReply(t *testing.T, client Client, id int) Game {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		game, err := client.State(context.Background(), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if game.GameStatus != "ONGOING" || game.Turn == game.Color {
+			return game
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the opponent has not answered in game %d", id)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestEngineOpponentPlaysIntendedCell lets the engine opponent finish its four on the server. The
+// cell it has to take is off the diagonal, so a move with row and column swapped lands elsewhere.
+func TestEngineOpponentPlaysIntendedCell(t *testing.T) {
+	tests := []struct {
+		name string
+		rows []string
+		x, y int
+	}{
+		{
+			name: "four in a row",
+			rows: []string{
+				"...............",
+				"...............",
+				"..XOOOO........",
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				"..........X.X..",
+				"...............",
+				"..............X",
+			},
+			x: 7, y: 2,
+		},
+		{
+			name: "four in a column",
+			rows: []string{
+				"...............",
+				"..X............",
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				"...............",
+				"...........X...",
+				"...........O...",
+				"...........O...",
+				"...........O...",
+				"...........O...",
+				"...............",
+				"X.X............",
+			},
+			x: 11, y: 13,
+		},
+	}
+	cfg := defaultConfig().search
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newGameServer(1, defaultConfig().server)
+			g := &serverGame{
+				id:     1,
+				color:  "black",
+				turn:   "white",
+				status: "ONGOING",
+				board:  boardFromRows(tt.rows),
+				opponent: &engineOpponent{
+					engine:   newAlphaBetaEngine(cfg),
+					depth:    2,
+					fallback: &randomOpponent{rng: rand.New(rand.NewSource(1))},
+				},
+			}
+			s.mu.Lock()
+			s.games[g.id] = g
+			s.opponentMove(g)
+			s.mu.Unlock()
+			game := waitForReply(t, &fakeClient{server: s}, g.id)
+			if game.Gameboard[tt.y][tt.x] != WHITE || game.GameStatus != "WHITEWON" {
+				t.Errorf("the engine opponent did not win on %d,%d, the game is %s", tt.x, tt.y, game.GameStatus)
+			}
+		})
+	}
+}
+
+// TestStateAnsweredWhileOpponentThinks checks the opponent thinks without holding the server, so a
+// state request is answered while it waits and shows the game with the opponent to move
+func TestStateAnsweredWhileOpponentThinks(t *testing.T) {
+	ctx := context.Background()
+	cfg := testServerConfig(0)
+	cfg.opponentDelay = 500 * time.Millisecond
+	fake := &fakeClient{server: newGameServer(1, cfg)}
+	game, err := fake.Start(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if _, err := fake.Move(ctx, game.GameID, Point{7, 7}); err != nil {
+		t.Fatal(err)
+	}
+	game, err = fake.State(ctx, game.GameID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(start); waited > cfg.opponentDelay/2 {
+		t.Errorf("the move and the state took %v, they waited for the opponent", waited)
+	}
+	if game.Turn != "white" {
+		t.Errorf("the opponent already played while it was still thinking")
+	}
+	if game = waitForReply(t, fake, game.GameID); game.Turn != "black" {
+		t.Errorf("the opponent did not answer, the game is %s with %s to move", game.GameStatus, game.Turn)
+	}
+}
@@ -0,0 +1,511 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// threatTimeShare is the part of the time left for the move one threat search may take
+const threatTimeShare = 6
+
+// threatSearch looks for a forced win of the attacker. In a VCF (victory by continuous fours) every
+// attacker move is a four the defender has to block, so the defender's moves are forced and the search
+// stays narrow. A VCT (victory by continuous threats) also allows threes, and then every defence that
+// could stop the three has to be refuted.
+type threatSearch struct {
+	attacker int
+	defender int
+	hash     uint64
+	nodes    int
+	maxNodes int
+	vcfDepth int       // most fours in a row the VCF inside a VCT plays
+	deadline time.Time // zero for no time limit
+	stopped  bool
+	failed   map[uint64]int // positions already shown to have no win, with the depth they were searched to
+}
+
+func newThreatSearch(board [][]int, attacker, maxNodes int) *threatSearch {
+	return &threatSearch{
+		attacker: attacker,
+		defender: getOpponent(attacker),
+		hash:     hashBoard(board) ^ zobristPlayer[attacker],
+		maxNodes: maxNodes,
+		failed:   make(map[uint64]int),
+	}
+}
+
+// tick counts a node and stops the search when it used up its nodes or the time for the move
+func (s *threatSearch) tick() bool {
+	s.nodes++
+	if s.nodes > s.maxNodes {
+		s.stopped = true
+	}
+	if !s.deadline.IsZero() && s.nodes%256 == 0 && time.Now().After(s.deadline) {
+		s.stopped = true
+	}
+	return s.stopped
+}
+
+func (s *threatSearch) place(board [][]int, move [2]int, stone int) {
+	board[move[0]][move[1]] = stone
+	s.hash ^= stoneKey(move[0], move[1], stone)
+}
+
+func (s *threatSearch) remove(board [][]int, move [2]int, stone int) {
+	board[move[0]][move[1]] = EMPTY
+	s.hash ^= stoneKey(move[0], move[1], stone)
+}
+
+func onBoard(board [][]int, x, y int) bool {
+	return x >= 0 && x < len(board) && y >= 0 && y < len(board)
+}
+
+// runLength is the number of the stone's stones in a row through x, y in the direction, counting x, y itself
+func runLength(board [][]int, x, y, dx, dy, stone int) int {
+	count := 1
+	for i := 1; i < 5 && onBoard(board, x+dx*i, y+dy*i) && board[x+dx*i][y+dy*i] == stone; i++ {
+		count++
+	}
+	for i := 1; i < 5 && onBoard(board, x-dx*i, y-dy*i) && board[x-dx*i][y-dy*i] == stone; i++ {
+		count++
+	}
+	return count
+}
+
+// makesFive is true when the stone on the empty cell x, y would make five in a row
+func makesFive(board [][]int, x, y, stone int) bool {
+	for _, dir := range DIRECTIONS {
+		if runLength(board, x, y, dir[0], dir[1], stone) >= 5 {
+			return true
+		}
+	}
+	return false
+}
+
+// fiveCells are the empty cells where the stone would make five. Such a cell always touches
+// one of the stone's stones, so the others are skipped without looking at the lines.
+func fiveCells(board [][]int, stone int) [][2]int {
+	var cells [][2]int
+	for x := range board {
+		for y := range board[x] {
+			if board[x][y] == EMPTY && touches(board, x, y, stone) && makesFive(board, x, y, stone) {
+				cells = append(cells, [2]int{x, y})
+			}
+		}
+	}
+	return cells
+}
+
+func touches(board [][]int, x, y, stone int) bool {
+	for dx := -1; dx <= 1; dx++ {
+		for dy := -1; dy <= 1; dy++ {
+			if onBoard(board, x+dx, y+dy) && board[x+dx][y+dy] == stone {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// lineCells are the empty cells up to four away from x, y in the direction, both ways
+func lineCells(board [][]int, x, y, dx, dy int) [][2]int {
+	cells := make([][2]int, 0, 8)
+	for i := -4; i <= 4; i++ {
+		nx, ny := x+dx*i, y+dy*i
+		if i != 0 && onBoard(board, nx, ny) && board[nx][ny] == EMPTY {
+			cells = append(cells, [2]int{nx, ny})
+		}
+	}
+	return cells
+}
+
+// gainCells are the cells that would complete a five through the stone just played on x, y.
+// One makes the move a four, two or more can't all be blocked.
+func gainCells(board [][]int, x, y, stone int) [][2]int {
+	var cells [][2]int
+	for _, dir := range DIRECTIONS {
+		for i := -4; i <= 4; i++ {
+			nx, ny := x+dir[0]*i, y+dir[1]*i
+			if i == 0 || !onBoard(board, nx, ny) || board[nx][ny] != EMPTY {
+				continue
+			}
+			c := [2]int{nx, ny}
+			if runLength(board, nx, ny, dir[0], dir[1], stone) >= 5 && !containsMove(cells, c) {
+				cells = append(cells, c)
+			}
+		}
+	}
+	return cells
+}
+
+// threeDefences are the cells that stop the stone just played on x, y from becoming an open four: the
+// cells that would make one and the cells that would then complete the five. None means it is not a three.
+func threeDefences(board [][]int, x, y, stone int) [][2]int {
+	var cells [][2]int
+	for _, dir := range DIRECTIONS {
+		for _, d := range lineCells(board, x, y, dir[0], dir[1]) {
+			board[d[0]][d[1]] = stone
+			var ends [][2]int
+			for _, e := range lineCells(board, d[0], d[1], dir[0], dir[1]) {
+				if runLength(board, e[0], e[1], dir[0], dir[1], stone) >= 5 {
+					ends = append(ends, e)
+				}
+			}
+			board[d[0]][d[1]] = EMPTY
+			if len(ends) < 2 {
+				continue
+			}
+			for _, c := range append(ends, d) {
+				if !containsMove(cells, c) {
+					cells = append(cells, c)
+				}
+			}
+		}
+	}
+	return cells
+}
+
+func containsMove(moves [][2]int, move [2]int) bool {
+	for _, m := range moves {
+		if m == move {
+			return true
+		}
+	}
+	return false
+}
+
+// threatCandidates are the empty cells up to two away from the stone's stones, most threatening first
+func threatCandidates(board [][]int, stone int) [][2]int {
+	size := len(board)
+	near := make([]bool, size*size)
+	for x := range board {
+		for y := range board[x] {
+			if board[x][y] != stone {
+				continue
+			}
+			for nx := max(x-2, 0); nx <= min(x+2, size-1); nx++ {
+				for ny := max(y-2, 0); ny <= min(y+2, size-1); ny++ {
+					near[nx*size+ny] = true
+				}
+			}
+		}
+	}
+	type scoredCell struct {
+		cell  [2]int
+		score int
+	}
+	var scored []scoredCell
+	pos := newPosition(board)
+	for i, ok := range near {
+		x, y := i/size, i%size
+		if ok && board[x][y] == EMPTY {
+			scored = append(scored, scoredCell{[2]int{x, y}, lineScore(pos, x, y, stone)})
+		}
+	}
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].score > scored[j].score
+	})
+	cells := make([][2]int, len(scored))
+	for i, sc := range scored {
+		cells[i] = sc.cell
+	}
+	return cells
+}
+
+// attackerMoves are the moves the attacker can start a threat with. When the defender threatens
+// five the only one is the block.
+func (s *threatSearch) attackerMoves(board [][]int) ([][2]int, bool) {
+	forced := fiveCells(board, s.defender)
+	if len(forced) > 1 {
+		return nil, false
+	}
+	if len(forced) == 1 {
+		return forced, true
+	}
+	return threatCandidates(board, s.attacker), true
+}
+
+func (s *threatSearch) knownFailure(depth int, kind uint64) bool {
+	failedAt, ok := s.failed[s.hash^kind]
+	return ok && failedAt >= depth
+}
+
+func (s *threatSearch) recordFailure(depth int, kind uint64) {
+	if !s.stopped {
+		s.failed[s.hash^kind] = depth
+	}
+}
+
+// vcf finds a win by continuous fours with at most depth attacker moves. It returns the line
+// of attacker and defender moves, the last attacker move makes five or an unstoppable four.
+func (s *threatSearch) vcf(board [][]int, depth int) ([][2]int, bool) {
+	if s.tick() {
+		return nil, false
+	}
+	if fives := fiveCells(board, s.attacker); len(fives) > 0 {
+		return fives[:1], true
+	}
+	if depth == 0 || s.knownFailure(depth, 1) {
+		return nil, false
+	}
+	candidates, ok := s.attackerMoves(board)
+	if !ok {
+		return nil, false
+	}
+	for _, c := range candidates {
+		s.place(board, c, s.attacker)
+		gains := gainCells(board, c[0], c[1], s.attacker)
+		if len(gains) >= 2 {
+			s.remove(board, c, s.attacker)
+			return [][2]int{c}, true
+		}
+		if len(gains) == 1 && !makesFive(board, gains[0][0], gains[0][1], s.defender) {
+			block := gains[0]
+			s.place(board, block, s.defender)
+			line, won := s.vcf(board, depth-1)
+			s.remove(board, block, s.defender)
+			if won {
+				s.remove(board, c, s.attacker)
+				return append([][2]int{c, block}, line...), true
+			}
+		}
+		s.remove(board, c, s.attacker)
+		if s.stopped {
+			return nil, false
+		}
+	}
+	s.recordFailure(depth, 1)
+	return nil, false
+}
+
+// vct finds a win by continuous fours and threes with at most depth attacker moves. A three
+// has to win against every defence that stops it and against every counter four of the defender.
+func (s *threatSearch) vct(board [][]int, depth int) ([][2]int, bool) {
+	if line, ok := s.vcf(board, s.vcfDepth); ok || s.stopped {
+		return line, ok
+	}
+	if depth == 0 || s.knownFailure(depth, 2) {
+		return nil, false
+	}
+	candidates, ok := s.attackerMoves(board)
+	if !ok {
+		return nil, false
+	}
+	for _, c := range candidates {
+		s.place(board, c, s.attacker)
+		line, won := s.vctAfter(board, c, depth)
+		s.remove(board, c, s.attacker)
+		if won {
+			return append([][2]int{c}, line...), true
+		}
+		if s.stopped {
+			return nil, false
+		}
+	}
+	s.recordFailure(depth, 2)
+	return nil, false
+}
+
+// vctAfter answers the attacker's threat on c with every defence and returns the line against the first
+func (s *threatSearch) vctAfter(board [][]int, c [2]int, depth int) ([][2]int, bool) {
+	gains := gainCells(board, c[0], c[1], s.attacker)
+	if len(gains) >= 2 {
+		return nil, true
+	}
+	var defences [][2]int
+	if len(gains) == 1 {
+		defences = gains
+	} else {
+		defences = threeDefences(board, c[0], c[1], s.attacker)
+		if len(defences) == 0 {
+			// not a threat at all
+			return nil, false
+		}
+		for _, counter := range threatCandidates(board, s.defender) {
+			if containsMove(defences, counter) {
+				continue
+			}
+			board[counter[0]][counter[1]] = s.defender
+			if len(gainCells(board, counter[0], counter[1], s.defender)) > 0 {
+				defences = append(defences, counter)
+			}
+			board[counter[0]][counter[1]] = EMPTY
+		}
+	}
+
+	var firstLine [][2]int
+	for i, d := range defences {
+		if makesFive(board, d[0], d[1], s.defender) {
+			return nil, false
+		}
+		s.place(board, d, s.defender)
+		line, won := s.vct(board, depth-1)
+		s.remove(board, d, s.defender)
+		if !won {
+			return nil, false
+		}
+		if i == 0 {
+			firstLine = append([][2]int{d}, line...)
+		}
+	}
+	return firstLine, true
+}
+
+// threatWin looks for a forced win of the attacker, a VCF first and then a VCT.
+// kind says which one was found. With a deadline for the move it only takes a share of the time left.
+func (e *alphaBetaEngine) threatWin(board [][]int, attacker int, maxNodes int) (line [][2]int, kind string, nodes int) {
+	work := makeCopy(board)
+	s := newThreatSearch(work, attacker, maxNodes)
+	s.vcfDepth = e.cfg.vcfDepth
+	if !e.deadline.IsZero() {
+		s.deadline = time.Now().Add(time.Until(e.deadline) / threatTimeShare)
+	}
+	if line, ok := s.vcf(work, e.cfg.vcfDepth); ok {
+		return line, "VCF", s.nodes
+	}
+	if s.stopped {
+		return nil, "", s.nodes
+	}
+	s.maxNodes += maxNodes
+	if line, ok := s.vct(work, e.cfg.vctDepth); ok {
+		return line, "VCT", s.nodes
+	}
+	return nil, "", s.nodes
+}
+
+// defendThreats looks for a move that takes away the opponent's forced win. It tries the cells of
+// the winning line first, then our own fours and then the most threatening moves.
+func (e *alphaBetaEngine) defendThreats(board [][]int, player int, line [][2]int) ([2]int, bool) {
+	opponent := getOpponent(player)
+	var candidates [][2]int
+	add := func(c [2]int) {
+		if board[c[0]][c[1]] == EMPTY && !containsMove(candidates, c) {
+			candidates = append(candidates, c)
+		}
+	}
+	for _, c := range line {
+		add(c)
+	}
+	for _, c := range threatCandidates(board, player) {
+		board[c[0]][c[1]] = player
+		if len(gainCells(board, c[0], c[1], player)) > 0 {
+			add(c)
+		}
+		board[c[0]][c[1]] = EMPTY
+	}
+	for _, c := range e.orderMoves(newPosition(board), threatCandidates(board, opponent), 0, player, [2]int{-1, -1}, false) {
+		if len(candidates) >= 20 {
+			break
+		}
+		add(c)
+	}
+
+	for _, c := range candidates {
+		board[c[0]][c[1]] = player
+		_, kind, _ := e.threatWin(board, opponent, e.cfg.threatNodes/4)
+		board[c[0]][c[1]] = EMPTY
+		if kind == "" {
+			return c, true
+		}
+	}
+	return [2]int{}, false
+}
+
+// readPosition reads a board from a file, a row per line with . or 0 for empty, X, B or 1 for black
+// and O, W or 2 for white. Spaces are ignored.
+func readPosition(filePath string) ([][]int, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	board, err := parsePosition(lines)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+	return board, nil
+}
+
+// parsePosition reads a board from its rows written like readPosition takes them
+func parsePosition(lines []string) ([][]int, error) {
+	var board [][]int
+	for line, text := range lines {
+		text = strings.ReplaceAll(strings.TrimSpace(text), " ", "")
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		row := make([]int, 0, len(text))
+		for _, r := range strings.ToUpper(text) {
+			switch r {
+			case '.', '0', '+':
+				row = append(row, EMPTY)
+			case 'X', 'B', '1':
+				row = append(row, BLACK)
+			case 'O', 'W', '2':
+				row = append(row, WHITE)
+			default:
+				return nil, fmt.Errorf("line %d: unknown cell %q", line+1, r)
+			}
+		}
+		board = append(board, row)
+	}
+	for i, row := range board {
+		if len(row) != len(board) {
+			return nil, fmt.Errorf("row %d has %d cells, the board is %d rows high", i, len(row), len(board))
+		}
+	}
+	if len(board) == 0 {
+		return nil, fmt.Errorf("no board")
+	}
+	if err := checkBoardSize(len(board)); err != nil {
+		return nil, err
+	}
+	return board, nil
+}
+
+// solvePosition prints the forced wins both sides have in the position
+func solvePosition(filePath string, cfg searchConfig) {
+	board, err := readPosition(filePath)
+	if err != nil {
+		fmt.Println("Error reading position:", err)
+		os.Exit(1)
+	}
+	printBoardClearly(board)
+	toMove := "black"
+	if countStones(board, BLACK) > countStones(board, WHITE) {
+		toMove = "white"
+	}
+	fmt.Println(toMove, "to move, moves are (row,col)")
+	e := newAlphaBetaEngine(cfg)
+	for _, color := range []string{toMove, otherColor(toMove)} {
+		start := time.Now()
+		line, kind, nodes := e.threatWin(board, toPlayer(color), cfg.threatNodes)
+		if kind == "" {
+			fmt.Printf("%s: no forced win found (%d nodes, %v)\n", color, nodes, time.Since(start).Round(time.Millisecond))
+			continue
+		}
+		fmt.Printf("%s: %s starting with %v (%d nodes, %v)\n", color, kind, line[0], nodes, time.Since(start).Round(time.Millisecond))
+		fmt.Print("  line:")
+		for i, move := range line {
+			side := color
+			if i%2 == 1 {
+				side = otherColor(color)
+			}
+			fmt.Printf(" %c%v", strings.ToUpper(side)[0], move)
+		}
+		fmt.Println()
+	}
+}
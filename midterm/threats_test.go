@@ -0,0 +1,124 @@
+package main
+
+import "testing"
+
+// vcfPosition is a win for black by fours alone: the row four, the column four and then a double four
+var vcfPosition = []string{
+	"...............",
+	"...............",
+	"...............",
+	".......O.......",
+	".......X..X....",
+	".......X.X.....",
+	"...............",
+	"...OXXX........",
+	"...............",
+	"...............",
+	"...............",
+	"...............",
+	"..O.........O..",
+	"...............",
+	"...............",
+}
+
+// vctPosition has no four for black to make, but 7,7 makes two open threes
+var vctPosition = []string{
+	"...............",
+	"...............",
+	"...............",
+	"...............",
+	"...............",
+	".......X.......",
+	".......X.......",
+	".....XX........",
+	"...............",
+	"...............",
+	"..O............",
+	".............O.",
+	"...............",
+	"..O.........O..",
+	"...............",
+}
+
+// quietPosition has a few stones far apart, neither side can force anything
+var quietPosition = []string{
+	"...............",
+	"...............",
+	"..X............",
+	"...............",
+	"...............",
+	"...............",
+	"...............",
+	".......X.......",
+	"........O......",
+	"...............",
+	"...............",
+	"...............",
+	"............O..",
+	"...............",
+	"...............",
+}
+
+func TestThreatWin(t *testing.T) {
+	tests := []struct {
+		name     string
+		rows     []string
+		attacker int
+		kind     string
+		first    [2]int
+	}{
+		{"VCF", vcfPosition, BLACK, "VCF", [2]int{7, 7}},
+		{"VCT", vctPosition, BLACK, "VCT", [2]int{7, 7}},
+		{"black has no forced win", quietPosition, BLACK, "", [2]int{}},
+		{"white has no forced win", quietPosition, WHITE, "", [2]int{}},
+		{"the defender of the VCF has none", vcfPosition, WHITE, "", [2]int{}},
+	}
+	e := newAlphaBetaEngine(defaultConfig().search)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board, err := parsePosition(tt.rows)
+			if err != nil {
+				t.Fatal(err)
+			}
+			line, kind, _ := e.threatWin(board, tt.attacker, 20000)
+			if kind != tt.kind {
+				t.Fatalf("found %q, want %q, line %v", kind, tt.kind, line)
+			}
+			if kind == "" {
+				return
+			}
+			if line[0] != tt.first {
+				t.Errorf("the win starts with %v, want %v", line[0], tt.first)
+			}
+			// the attacker's last move leaves more fives than the defender can block
+			stone := tt.attacker
+			for _, move := range line {
+				if board[move[0]][move[1]] != EMPTY {
+					t.Fatalf("the line plays %v twice", move)
+				}
+				board[move[0]][move[1]] = stone
+				stone = getOpponent(stone)
+			}
+			if !hasWon(board, tt.attacker) && len(fiveCells(board, tt.attacker)) < 2 {
+				t.Errorf("after the line %v the attacker has no unstoppable five", line)
+			}
+		})
+	}
+}
+
+// TestThreatWinNodeCap gives the search too few nodes for the VCT, it has to give up within its share
+func TestThreatWinNodeCap(t *testing.T) {
+	board, err := parsePosition(vctPosition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const maxNodes = 3
+	_, kind, nodes := newAlphaBetaEngine(defaultConfig().search).threatWin(board, BLACK, maxNodes)
+	if kind != "" {
+		t.Errorf("found a %s with %d nodes", kind, maxNodes)
+	}
+	// the VCF and then the VCT each get maxNodes
+	if nodes > 2*maxNodes+1 {
+		t.Errorf("searched %d nodes with a cap of %d", nodes, maxNodes)
+	}
+}
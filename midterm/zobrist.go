@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// zobristKeys has a random number for every cell and stone, a position's hash is the xor of the
+// numbers of its stones so placing or removing a stone is a single xor
+var zobristKeys [][][3]uint64
+var zobristMaximizing uint64
+var zobristPlayer [3]uint64
+
+// initZobrist makes the keys for the board size, always from the same seed so hashes are repeatable
+func initZobrist(size int) {
+	if len(zobristKeys) == size {
+		return
+	}
+	rng := rand.New(rand.NewSource(15))
+	zobristKeys = make([][][3]uint64, size)
+	for row := range zobristKeys {
+		zobristKeys[row] = make([][3]uint64, size)
+		for col := range zobristKeys[row] {
+			zobristKeys[row][col] = [3]uint64{0, rng.Uint64(), rng.Uint64()}
+		}
+	}
+	zobristMaximizing = rng.Uint64()
+	zobristPlayer = [3]uint64{0, rng.Uint64(), rng.Uint64()}
+}
+
+func stoneKey(row, col, stone int) uint64 {
+	return zobristKeys[row][col][stone]
+}
+
+// hashBoard is the hash of the stones on the board
+func hashBoard(board [][]int) uint64 {
+	initZobrist(len(board))
+	var hash uint64
+	for row := range board {
+		for col, cell := range board[row] {
+			hash ^= zobristKeys[row][col][cell]
+		}
+	}
+	return hash
+}
+
+// positionKey adds what else minimax's score depends on to the hash of the stones, who is
+// to move and whose point of view the score is from
+func positionKey(hash uint64, maximizingPlayer bool, player int) uint64 {
+	if maximizingPlayer {
+		hash ^= zobristMaximizing
+	}
+	return hash ^ zobristPlayer[player]
+}
+
+// ttBound tells how a stored score relates to the real one
+type ttBound uint8
+
+const (
+	ttExact ttBound = iota + 1
+	ttLower         // the search failed high, the real score is at least this
+	ttUpper         // the search failed low, the real score is at most this
+)
+
+type ttEntry struct {
+	key   uint64
+	score int32
+	depth int8
+	bound ttBound
+	age   uint8
+	move  [2]int8 // best move found, -1 when there was none
+}
+
+// transpositionTable remembers searched positions in a fixed number of slots, picked by the low
+// bits of the hash. A slot is taken over by a deeper search or anything from a newer move.
+type transpositionTable struct {
+	entries []ttEntry
+	mask    uint64
+	age     uint8
+
+	probes, hits, cutoffs, stores int
+}
+
+func newTranspositionTable(bits int) *transpositionTable {
+	return &transpositionTable{entries: make([]ttEntry, 1<<bits), mask: 1<<bits - 1}
+}
+
+// newSearch starts a new move, entries from earlier moves stay but give way to new ones
+func (t *transpositionTable) newSearch() {
+	t.age++
+	t.probes, t.hits, t.cutoffs, t.stores = 0, 0, 0, 0
+}
+
+func (t *transpositionTable) probe(key uint64) (ttEntry, bool) {
+	t.probes++
+	entry := t.entries[key&t.mask]
+	if entry.bound == 0 || entry.key != key {
+		return entry, false
+	}
+	t.hits++
+	return entry, true
+}
+
+func (t *transpositionTable) store(key uint64, depth int, score int, bound ttBound, move [2]int) {
+	slot := &t.entries[key&t.mask]
+	if slot.bound != 0 && slot.key != key && slot.age == t.age && int(slot.depth) > depth {
+		return
+	}
+	t.stores++
+	*slot = ttEntry{key: key, score: int32(score), depth: int8(depth), bound: bound, age: t.age, move: [2]int8{int8(move[0]), int8(move[1])}}
+}
+
+func (e ttEntry) bestMove() ([2]int, bool) {
+	return [2]int{int(e.move[0]), int(e.move[1])}, e.move[0] >= 0
+}
+
+func (t *transpositionTable) hitRate() float64 {
+	if t.probes == 0 {
+		return 0
+	}
+	return 100 * float64(t.hits) / float64(t.probes)
+}
+
+func (t *transpositionTable) printStats() {
+	used := 0
+	for _, entry := range t.entries {
+		if entry.bound != 0 {
+			used++
+		}
+	}
+	fmt.Printf("Transposition table: %d probes, %.1f%% hits, %d cutoffs, %d stores, %.1f%% full\n",
+		t.probes, t.hitRate(), t.cutoffs, t.stores, 100*float64(used)/float64(len(t.entries)))
+}
+
+// benchPositions plays random openings of 6 to 16 stones around the middle, the same ones every run
+func benchPositions(count, size int) [][][]int {
+	rng := rand.New(rand.NewSource(7))
+	random := &randomOpponent{rng: rng}
+	positions := make([][][]int, 0, count)
+	for i := 0; i < count; i++ {
+		board := make([][]int, size)
+		for row := range board {
+			board[row] = make([]int, size)
+		}
+		board[size/2][size/2] = BLACK
+		stone := WHITE
+		for stones := 6 + rng.Intn(11); stones > 1; stones-- {
+			p := random.play(board, "")
+			board[p.Row][p.Col] = stone
+			stone = getOpponent(stone)
+		}
+		if hasWon(board, BLACK) || hasWon(board, WHITE) {
+			i--
+			continue
+		}
+		positions = append(positions, board)
+	}
+	return positions
+}
+
+// runBenchTT searches the same positions to the same depth without and with the table and
+// compares the nodes, the time and the moves found
+func runBenchTT(cfg searchConfig, size int) {
+	searchDepth := cfg.depth
+	fmt.Printf("Searching to depth %d without and with a transposition table of %d entries\n", searchDepth, 1<<cfg.ttBits)
+	fmt.Println("pos  nodes off   time off   nodes on    time on   hits  speedup  same move")
+	var timeOff, timeOn time.Duration
+	for i, board := range benchPositions(10, size) {
+		player := BLACK
+		if countStones(board, BLACK) > countStones(board, WHITE) {
+			player = WHITE
+		}
+		moves := generateMoves(board)
+		pos := newPosition(board)
+
+		without := newAlphaBetaEngine(cfg)
+		start := time.Now()
+		moveOff, scoreOff, _ := without.searchRoot(pos, moves, searchDepth, player, getOpponent(player))
+		off := time.Since(start)
+
+		with := newAlphaBetaEngine(cfg)
+		with.tt = newTranspositionTable(cfg.ttBits)
+		with.tt.newSearch()
+		start = time.Now()
+		moveOn, scoreOn, _ := with.searchRoot(pos, moves, searchDepth, player, getOpponent(player))
+		on := time.Since(start)
+
+		timeOff += off
+		timeOn += on
+		fmt.Printf("%3d %10d %10v %10d %10v %5.1f%% %7.2fx  %v\n", i+1, without.nodes, off.Round(time.Millisecond), with.nodes,
+			on.Round(time.Millisecond), with.tt.hitRate(), off.Seconds()/on.Seconds(), moveOff == moveOn && scoreOff == scoreOn)
+	}
+	fmt.Printf("Total %v without and %v with the table, %.2fx faster\n", timeOff.Round(time.Millisecond), timeOn.Round(time.Millisecond), timeOff.Seconds()/timeOn.Seconds())
+}
@@ -0,0 +1,64 @@
+package main
+
+import "testing"
+
+func TestTranspositionTableReplacement(t *testing.T) {
+	tt := newTranspositionTable(4)
+	tt.newSearch()
+	// both keys land in slot 3
+	deep, shallow := uint64(0x13), uint64(0x23)
+	noMove := [2]int{-1, -1}
+
+	tt.store(deep, 5, 100, ttExact, noMove)
+	tt.store(shallow, 2, 200, ttExact, noMove)
+	if entry, ok := tt.probe(deep); !ok || entry.depth != 5 || entry.score != 100 {
+		t.Errorf("a shallower entry from the same move replaced the deeper one: %+v, %v", entry, ok)
+	}
+	if _, ok := tt.probe(shallow); ok {
+		t.Errorf("the shallower entry was stored over the deeper one")
+	}
+
+	tt.store(deep, 3, 150, ttLower, [2]int{7, 7})
+	if entry, ok := tt.probe(deep); !ok || entry.depth != 3 || entry.bound != ttLower {
+		t.Errorf("a new result for the same position was not stored: %+v, %v", entry, ok)
+	}
+	tt.store(deep, 5, 100, ttExact, noMove)
+
+	tt.newSearch()
+	tt.store(shallow, 1, 300, ttUpper, noMove)
+	if entry, ok := tt.probe(shallow); !ok || entry.depth != 1 || entry.score != 300 {
+		t.Errorf("an entry from an earlier move did not give way: %+v, %v", entry, ok)
+	}
+	if _, ok := tt.probe(deep); ok {
+		t.Errorf("the entry from the earlier move is still there")
+	}
+}
+
+// TestSearchSameWithTT searches the bench positions without and with the table, the table only
+// saves work so the scores have to be the same
+func TestSearchSameWithTT(t *testing.T) {
+	const depth = 3
+	cfg := defaultConfig().search
+	for i, board := range benchPositions(4, 15) {
+		player := BLACK
+		if countStones(board, BLACK) > countStones(board, WHITE) {
+			player = WHITE
+		}
+		moves := generateMoves(board)
+		pos := newPosition(board)
+
+		without := newAlphaBetaEngine(cfg)
+		_, scoreOff, _ := without.searchRoot(pos, moves, depth, player, getOpponent(player))
+		with := newAlphaBetaEngine(cfg)
+		with.tt = newTranspositionTable(16)
+		with.tt.newSearch()
+		_, scoreOn, _ := with.searchRoot(pos, moves, depth, player, getOpponent(player))
+
+		if scoreOff != scoreOn {
+			t.Errorf("position %d: %d without the table, %d with it", i+1, scoreOff, scoreOn)
+		}
+		if with.tt.hits == 0 {
+			t.Errorf("position %d: the table was never hit", i+1)
+		}
+	}
+}